@@ -3,26 +3,41 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
+	"os"
 	"os/signal"
 	"syscall"
 
 	apiconfig "redpacket/internal/app/api/config"
 	apiserver "redpacket/internal/app/api/server"
+	"redpacket/internal/observability/logging"
 )
 
 func main() {
 	cfg := apiconfig.Load()
+	logger, err := logging.New(os.Stdout, logging.Config{
+		Level:            cfg.Logging.Level,
+		SampleFirst:      cfg.Logging.SampleFirst,
+		SampleThereafter: cfg.Logging.SampleThereafter,
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize logging: %v", err)
+	}
+	slog.SetDefault(logger)
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	srv, err := apiserver.New(ctx, cfg)
+	srv, err := apiserver.New(ctx, cfg, logger)
 	if err != nil {
-		log.Fatalf("failed to initialize api server: %v", err)
+		logger.Error("failed to initialize api server", logging.Err(err))
+		os.Exit(1)
 	}
 	defer srv.Close()
 
-	log.Printf("api listening on %s", cfg.Port)
+	logger.Info("api listening", slog.String("port", cfg.Port))
 	if err := srv.Run(ctx); err != nil {
-		log.Fatalf("api server stopped: %v", err)
+		logger.Error("api server stopped", logging.Err(err))
+		os.Exit(1)
 	}
 }
@@ -3,26 +3,41 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
+	"os"
 	"os/signal"
 	"syscall"
 
 	consumerconfig "redpacket/internal/app/consumer/config"
 	consumerserver "redpacket/internal/app/consumer/server"
+	"redpacket/internal/observability/logging"
 )
 
 func main() {
 	cfg := consumerconfig.Load()
+	logger, err := logging.New(os.Stdout, logging.Config{
+		Level:            cfg.Logging.Level,
+		SampleFirst:      cfg.Logging.SampleFirst,
+		SampleThereafter: cfg.Logging.SampleThereafter,
+	})
+	if err != nil {
+		log.Fatalf("failed to initialize logging: %v", err)
+	}
+	slog.SetDefault(logger)
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	srv, err := consumerserver.New(ctx, cfg)
+	srv, err := consumerserver.New(ctx, cfg, logger)
 	if err != nil {
-		log.Fatalf("failed to init consumer: %v", err)
+		logger.Error("failed to init consumer", logging.Err(err))
+		os.Exit(1)
 	}
 	defer srv.Close()
 
-	log.Printf("consumer listening on topic %s", cfg.KafkaTopic)
+	logger.Info("consumer listening", slog.String("topic", cfg.KafkaTopic))
 	if err := srv.Run(ctx); err != nil && ctx.Err() == nil {
-		log.Fatalf("consumer stopped: %v", err)
+		logger.Error("consumer stopped", logging.Err(err))
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"redpacket/internal/paymentprovider"
+)
+
+func main() {
+	addr := getEnv("MOCK_PAYOUT_ADDR", ":8090")
+	failureRate, _ := strconv.ParseFloat(getEnv("MOCK_PAYOUT_FAILURE_RATE", "0"), 64)
+	latency, _ := time.ParseDuration(getEnv("MOCK_PAYOUT_LATENCY", "0s"))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: paymentprovider.NewMockServer(failureRate, latency)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("mock payout provider listening on %s (failure rate %.2f)", addr, failureRate)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("mock payout provider stopped: %v", err)
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
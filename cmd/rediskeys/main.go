@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	redispkg "redpacket/internal/redis"
+)
+
+func main() {
+	addr := flag.String("addr", getEnv("REDIS_ADDR", "localhost:6379"), "standalone Redis address")
+	password := flag.String("password", os.Getenv("REDIS_PASSWORD"), "Redis password")
+	dryRun := flag.Bool("dry-run", false, "list the renames without applying them")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	client, err := redispkg.New(redispkg.Options{Addrs: []string{*addr}, Password: *password})
+	if err != nil {
+		log.Fatalf("failed to connect to redis: %v", err)
+	}
+	defer client.Close()
+
+	renamed, err := client.RenameLegacyKeys(ctx, *dryRun)
+	for _, r := range renamed {
+		fmt.Printf("%s -> %s\n", r.From, r.To)
+	}
+	if err != nil {
+		log.Fatalf("rename failed: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "renamed %d keys\n", len(renamed))
+}
+
+func getEnv(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
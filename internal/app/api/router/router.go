@@ -1,79 +1,441 @@
 package router
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	"redpacket/internal/db"
+	"redpacket/internal/domain/analytics"
 	"redpacket/internal/domain/campaign"
+	"redpacket/internal/domain/export"
+	"redpacket/internal/domain/leaderboard"
+	"redpacket/internal/domain/money"
+	"redpacket/internal/domain/schedule"
+	"redpacket/internal/domain/waitroom"
+	"redpacket/internal/domain/wallet"
+	"redpacket/internal/health"
 	"redpacket/internal/messaging/claim"
+	"redpacket/internal/observability/logging"
 	"redpacket/internal/observability/metrics"
+	"redpacket/internal/resilience"
 )
 
 // Dependencies enumerates services required by API handlers.
 type Dependencies struct {
-	CampaignService *campaign.Service
-	Publisher       *claim.Publisher
+	CampaignService  *campaign.Service
+	WalletService    *wallet.Service
+	AnalyticsService *analytics.Service
+	Exporter         *export.Exporter
+	Leaderboard      *leaderboard.Service
+	ScheduleService  *schedule.Service
+	Publisher        *claim.Publisher
+	WaitingRoom      *waitroom.Service
+	// Guards are listed on /debug/breakers.
+	Guards []*resilience.Guard
+	Health *health.Checker
+	Logger *slog.Logger
 }
 
 // New builds a gin.Engine with all routes registered.
 func New(deps Dependencies) *gin.Engine {
 	router := gin.New()
-	router.Use(gin.Logger(), gin.Recovery(), metrics.GinMiddleware())
+	// The logging middleware runs inside the span, so access lines carry the
+	// trace ID, and outside recovery, so panics are logged as 500s.
+	router.Use(
+		otelgin.Middleware("redpacket-api", otelgin.WithFilter(func(r *http.Request) bool { return !probeRequest(r) })),
+		logging.GinMiddleware(deps.Logger, probeRequest),
+		gin.CustomRecoveryWithWriter(io.Discard, recoverPanic(deps.Logger)),
+		metrics.GinMiddleware(),
+	)
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
-	h := &handler{svc: deps.CampaignService, publisher: deps.Publisher}
+	router.GET("/debug/breakers", listBreakers(deps.Guards))
+	router.GET("/healthz", gin.WrapH(deps.Health.Liveness()))
+	router.GET("/readyz", gin.WrapH(deps.Health.Readiness()))
+	h := &handler{svc: deps.CampaignService, wallets: deps.WalletService, analytics: deps.AnalyticsService, exporter: deps.Exporter, leaderboard: deps.Leaderboard, schedules: deps.ScheduleService, publisher: deps.Publisher, waitingRoom: deps.WaitingRoom, logger: deps.Logger}
 
 	router.POST("/campaign", h.createCampaign)
+	router.GET("/campaign/:id", h.getCampaign)
 	router.POST("/campaign/:id/open", h.openRedPacket)
+	router.POST("/campaign/:id/queue", h.joinWaitingRoom)
+	router.GET("/campaign/:id/queue", h.getQueueStatus)
+	router.POST("/campaign/:id/clone", h.cloneCampaign)
+	router.GET("/campaign/:id/claims/:user_id", h.getUserClaim)
+	router.GET("/campaign/:id/stats", h.getCampaignStats)
+	router.GET("/campaign/:id/export", h.exportClaims)
+	router.GET("/campaign/:id/leaderboard", h.getLeaderboard)
+	router.POST("/campaign-templates", h.createTemplate)
+	router.GET("/campaign-templates", h.listTemplates)
+	router.GET("/campaign-templates/:id", h.getTemplate)
+	router.POST("/campaign-templates/:id/campaigns", h.createFromTemplate)
+	router.POST("/campaign-schedules", h.createSchedule)
+	router.GET("/campaign-schedules", h.listSchedules)
+	router.GET("/campaign-schedules/:id", h.getSchedule)
+	router.PATCH("/campaign-schedules/:id", h.updateSchedule)
+	router.GET("/users/:id/claims", h.listUserClaims)
+	router.GET("/users/:id/wallet", h.getUserWallet)
 
 	return router
 }
 
+// probeRequest reports probes and scrapes, which are left out of traces and
+// logged at debug level.
+func probeRequest(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/metrics", "/healthz", "/readyz":
+		return true
+	}
+	return false
+}
+
+// recoverPanic logs a handler panic with its stack and answers 500.
+func recoverPanic(logger *slog.Logger) gin.RecoveryFunc {
+	return func(c *gin.Context, recovered any) {
+		logger.ErrorContext(c.Request.Context(), "handler panicked",
+			slog.Any("panic", recovered), slog.String("stack", string(debug.Stack())))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}
+}
+
 type handler struct {
-	svc       *campaign.Service
-	publisher *claim.Publisher
+	svc         *campaign.Service
+	wallets     *wallet.Service
+	analytics   *analytics.Service
+	exporter    *export.Exporter
+	leaderboard *leaderboard.Service
+	schedules   *schedule.Service
+	publisher   *claim.Publisher
+	waitingRoom *waitroom.Service
+	logger      *slog.Logger
 }
 
 type createCampaignRequest struct {
-	Name      string         `json:"name" binding:"required"`
-	Inventory map[string]int `json:"inventory" binding:"required"`
-	StartTime time.Time      `json:"start_time" binding:"required"`
-	EndTime   time.Time      `json:"end_time" binding:"required"`
+	Name          string          `json:"name" binding:"required"`
+	Currency      string          `json:"currency" binding:"required"`
+	Inventory     map[string]int  `json:"inventory" binding:"required"`
+	Rules         json.RawMessage `json:"rules"`
+	Shards        int             `json:"shards"`
+	ClaimMode     string          `json:"claim_mode"`
+	AdmissionRate *int            `json:"admission_rate"`
+	StartTime     time.Time       `json:"start_time" binding:"required"`
+	EndTime       time.Time       `json:"end_time" binding:"required"`
 }
 
 type createCampaignResponse struct {
 	ID int64 `json:"id"`
 }
 
+type inventoryResponse struct {
+	Amount       int64 `json:"amount"`
+	InitialTotal int   `json:"initial_total"`
+	OpenedCount  int   `json:"opened_count"`
+}
+
+type readinessResponse struct {
+	Ready       bool       `json:"ready"`
+	WarmedAt    *time.Time `json:"warmed_at"`
+	WarmDueAt   time.Time  `json:"warm_due_at"`
+	RedisPrimed bool       `json:"redis_primed"`
+}
+
+type campaignResponse struct {
+	ID            int64               `json:"id"`
+	Name          string              `json:"name"`
+	Currency      string              `json:"currency"`
+	Status        string              `json:"status"`
+	Rules         json.RawMessage     `json:"rules"`
+	TemplateID    *int64              `json:"template_id,omitempty"`
+	Shards        int                 `json:"shards"`
+	ClaimMode     string              `json:"claim_mode"`
+	AdmissionRate *int                `json:"admission_rate,omitempty"`
+	StartTime     time.Time           `json:"start_time"`
+	EndTime       time.Time           `json:"end_time"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Inventory     []inventoryResponse `json:"inventory"`
+	Readiness     readinessResponse   `json:"readiness"`
+}
+
+type cloneCampaignRequest struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+type createTemplateRequest struct {
+	Name            string          `json:"name" binding:"required"`
+	NamePattern     string          `json:"name_pattern"`
+	Currency        string          `json:"currency" binding:"required"`
+	Inventory       map[string]int  `json:"inventory" binding:"required"`
+	Rules           json.RawMessage `json:"rules"`
+	DurationSeconds int64           `json:"duration_seconds" binding:"required"`
+}
+
+type templateResponse struct {
+	ID              int64           `json:"id"`
+	Name            string          `json:"name"`
+	NamePattern     string          `json:"name_pattern"`
+	Currency        string          `json:"currency"`
+	Inventory       map[int64]int   `json:"inventory"`
+	Rules           json.RawMessage `json:"rules"`
+	DurationSeconds int64           `json:"duration_seconds"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+type createFromTemplateRequest struct {
+	StartTime time.Time `json:"start_time" binding:"required"`
+}
+
+type createScheduleRequest struct {
+	Name       string `json:"name" binding:"required"`
+	TemplateID int64  `json:"template_id" binding:"required"`
+	Cron       string `json:"cron" binding:"required"`
+	Timezone   string `json:"timezone"`
+	Enabled    *bool  `json:"enabled"`
+}
+
+type updateScheduleRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+type scheduleResponse struct {
+	ID         int64       `json:"id"`
+	Name       string      `json:"name"`
+	TemplateID int64       `json:"template_id"`
+	Cron       string      `json:"cron"`
+	Timezone   string      `json:"timezone"`
+	Enabled    bool        `json:"enabled"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Upcoming   []time.Time `json:"upcoming,omitempty"`
+}
+
 type openRedPacketRequest struct {
 	UserID string `json:"user_id" binding:"required"`
 }
 
+type joinWaitingRoomRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+type queueStatusResponse struct {
+	Token      string `json:"token"`
+	Ticket     int64  `json:"ticket"`
+	Position   int64  `json:"position"`
+	Admitted   bool   `json:"admitted"`
+	ETASeconds int64  `json:"eta_seconds"`
+}
+
+type breakerResponse struct {
+	Dependency          string     `json:"dependency"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	InFlight            int        `json:"in_flight"`
+	MaxInFlight         int        `json:"max_in_flight"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAfterSeconds   float64    `json:"retry_after_seconds"`
+}
+
+type openRedPacketResponse struct {
+	Status   string `json:"status"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type userClaimResponse struct {
+	Status    string     `json:"status"`
+	Amount    int64      `json:"amount,omitempty"`
+	Currency  string     `json:"currency,omitempty"`
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+}
+
+type claimResponse struct {
+	ID         int64     `json:"id"`
+	CampaignID int64     `json:"campaign_id"`
+	Amount     int64     `json:"amount"`
+	Currency   string    `json:"currency"`
+	ClaimedAt  time.Time `json:"claimed_at"`
+}
+
+type listUserClaimsResponse struct {
+	Claims     []claimResponse `json:"claims"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type walletBalanceResponse struct {
+	Currency   string `json:"currency"`
+	Balance    int64  `json:"balance"`
+	ClaimCount int    `json:"claim_count"`
+}
+
+type userWalletResponse struct {
+	UserID   string                  `json:"user_id"`
+	Balances []walletBalanceResponse `json:"balances"`
+}
+
+type statsBucketResponse struct {
+	Start       *time.Time      `json:"start,omitempty"`
+	Claims      int64           `json:"claims"`
+	UniqueUsers int64           `json:"unique_users"`
+	AmountSum   int64           `json:"amount_sum"`
+	Tiers       map[int64]int64 `json:"tiers"`
+}
+
+type soldOutResponse struct {
+	P50Seconds  *float64           `json:"p50_seconds"`
+	P95Seconds  *float64           `json:"p95_seconds"`
+	P100Seconds *float64           `json:"p100_seconds"`
+	Tiers       map[int64]*float64 `json:"tiers"`
+}
+
+type campaignStatsResponse struct {
+	CampaignID    int64                 `json:"campaign_id"`
+	Currency      string                `json:"currency"`
+	BucketSeconds int64                 `json:"bucket_seconds"`
+	TotalPackets  int64                 `json:"total_packets"`
+	Totals        statsBucketResponse   `json:"totals"`
+	SoldOut       soldOutResponse       `json:"sold_out"`
+	Buckets       []statsBucketResponse `json:"buckets"`
+}
+
+type leaderboardEntryResponse struct {
+	Rank   int64  `json:"rank"`
+	UserID string `json:"user_id"`
+	Amount int64  `json:"amount"`
+}
+
+type leaderboardResponse struct {
+	CampaignID int64                      `json:"campaign_id"`
+	Currency   string                     `json:"currency"`
+	Source     string                     `json:"source"`
+	Entries    []leaderboardEntryResponse `json:"entries"`
+	Me         *leaderboardEntryResponse  `json:"me,omitempty"`
+}
+
 func (h *handler) createCampaign(c *gin.Context) {
 	var req createCampaignRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	inventory := make(map[int]int, len(req.Inventory))
-	for amountStr, count := range req.Inventory {
-		amount, err := strconv.Atoi(amountStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "inventory keys must be integers"})
+	inventory, err := parseInventory(req.Inventory)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := h.svc.CreateCampaign(c.Request.Context(), campaign.CreateInput{
+		Name:          req.Name,
+		Currency:      money.Normalize(req.Currency),
+		Inventory:     inventory,
+		Rules:         req.Rules,
+		Shards:        req.Shards,
+		ClaimMode:     req.ClaimMode,
+		AdmissionRate: req.AdmissionRate,
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, createCampaignResponse{ID: id})
+}
+
+func (h *handler) getCampaign(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign id"})
+		return
+	}
+	details, err := h.svc.GetCampaign(c.Request.Context(), campaignID)
+	if err != nil {
+		if errors.Is(err, campaign.ErrCampaignNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		inventory[amount] = count
+		internalError(c, err)
+		return
 	}
-	id, err := h.svc.CreateCampaign(c.Request.Context(), campaign.CreateInput{
+	cp := details.Campaign
+	resp := campaignResponse{
+		ID:            cp.ID,
+		Name:          cp.Name,
+		Currency:      cp.Currency,
+		Status:        cp.Status,
+		Rules:         json.RawMessage(cp.Rules),
+		TemplateID:    cp.TemplateID,
+		Shards:        cp.Shards,
+		ClaimMode:     cp.ClaimMode,
+		AdmissionRate: cp.AdmissionRate,
+		StartTime:     cp.StartTime,
+		EndTime:       cp.EndTime,
+		CreatedAt:     cp.CreatedAt,
+		Inventory:     make([]inventoryResponse, 0, len(details.Inventory)),
+		Readiness:     readinessResponse(details.Readiness),
+	}
+	for _, inv := range details.Inventory {
+		resp.Inventory = append(resp.Inventory, inventoryResponse{Amount: inv.Amount, InitialTotal: inv.InitialTotal, OpenedCount: inv.OpenedCount})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *handler) cloneCampaign(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign id"})
+		return
+	}
+	var req cloneCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := h.svc.CloneCampaign(c.Request.Context(), campaignID, campaign.CloneInput{
 		Name:      req.Name,
-		Inventory: inventory,
 		StartTime: req.StartTime,
 		EndTime:   req.EndTime,
 	})
+	if err != nil {
+		if errors.Is(err, campaign.ErrCampaignNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, createCampaignResponse{ID: id})
+}
+
+func (h *handler) createTemplate(c *gin.Context) {
+	var req createTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inventory, err := parseInventory(req.Inventory)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := h.svc.CreateTemplate(c.Request.Context(), campaign.TemplateInput{
+		Name:        req.Name,
+		NamePattern: req.NamePattern,
+		Currency:    money.Normalize(req.Currency),
+		Inventory:   inventory,
+		Rules:       req.Rules,
+		Duration:    time.Duration(req.DurationSeconds) * time.Second,
+	})
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -81,6 +443,189 @@ func (h *handler) createCampaign(c *gin.Context) {
 	c.JSON(http.StatusCreated, createCampaignResponse{ID: id})
 }
 
+func (h *handler) listTemplates(c *gin.Context) {
+	templates, err := h.svc.ListTemplates(c.Request.Context())
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	resp := make([]templateResponse, 0, len(templates))
+	for _, t := range templates {
+		resp = append(resp, newTemplateResponse(t))
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": resp})
+}
+
+func (h *handler) getTemplate(c *gin.Context) {
+	templateID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id"})
+		return
+	}
+	t, err := h.svc.GetTemplate(c.Request.Context(), templateID)
+	if err != nil {
+		if errors.Is(err, campaign.ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newTemplateResponse(*t))
+}
+
+func (h *handler) createFromTemplate(c *gin.Context) {
+	templateID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid template id"})
+		return
+	}
+	var req createFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := h.svc.CreateFromTemplate(c.Request.Context(), templateID, req.StartTime)
+	if err != nil {
+		if errors.Is(err, campaign.ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, createCampaignResponse{ID: id})
+}
+
+func newTemplateResponse(t db.CampaignTemplate) templateResponse {
+	return templateResponse{
+		ID:              t.ID,
+		Name:            t.Name,
+		NamePattern:     t.NamePattern,
+		Currency:        t.Currency,
+		Inventory:       t.Inventory,
+		Rules:           json.RawMessage(t.Rules),
+		DurationSeconds: int64(t.Duration / time.Second),
+		CreatedAt:       t.CreatedAt,
+	}
+}
+
+func (h *handler) createSchedule(c *gin.Context) {
+	var req createScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+	id, err := h.schedules.Create(c.Request.Context(), schedule.Input{
+		Name:       req.Name,
+		TemplateID: req.TemplateID,
+		Cron:       req.Cron,
+		Timezone:   req.Timezone,
+		Enabled:    enabled,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, createCampaignResponse{ID: id})
+}
+
+func (h *handler) listSchedules(c *gin.Context) {
+	schedules, err := h.schedules.List(c.Request.Context())
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	resp := make([]scheduleResponse, 0, len(schedules))
+	for _, sc := range schedules {
+		resp = append(resp, newScheduleResponse(sc))
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": resp})
+}
+
+func (h *handler) getSchedule(c *gin.Context) {
+	scheduleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule id"})
+		return
+	}
+	sc, err := h.schedules.Get(c.Request.Context(), scheduleID)
+	if err != nil {
+		if errors.Is(err, schedule.ErrScheduleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+	resp := newScheduleResponse(*sc)
+	if upcoming, err := schedule.Upcoming(*sc, time.Now(), 5); err == nil {
+		resp.Upcoming = upcoming
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *handler) updateSchedule(c *gin.Context) {
+	scheduleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule id"})
+		return
+	}
+	var req updateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.schedules.SetEnabled(c.Request.Context(), scheduleID, *req.Enabled); err != nil {
+		if errors.Is(err, schedule.ErrScheduleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func newScheduleResponse(sc db.CampaignSchedule) scheduleResponse {
+	return scheduleResponse{
+		ID:         sc.ID,
+		Name:       sc.Name,
+		TemplateID: sc.TemplateID,
+		Cron:       sc.Cron,
+		Timezone:   sc.Timezone,
+		Enabled:    sc.Enabled,
+		CreatedAt:  sc.CreatedAt,
+	}
+}
+
+// parseInventory converts inventory keys, integer amounts in minor units, to int64.
+func parseInventory(raw map[string]int) (map[int64]int, error) {
+	inventory := make(map[int64]int, len(raw))
+	for amountStr, count := range raw {
+		amount, err := strconv.ParseInt(amountStr, 10, 64)
+		if err != nil {
+			return nil, errors.New("inventory keys must be integer amounts in minor units")
+		}
+		inventory[amount] = count
+	}
+	return inventory, nil
+}
+
+// Open outcomes counted by claim_opens_total.
+const (
+	openOK            = "ok"
+	openAlreadyOpened = "already_opened"
+	openSoldOut       = "sold_out"
+	openInactive      = "inactive"
+	openNotFound      = "not_found"
+	openNotAdmitted   = "not_admitted"
+	openInvalidToken  = "invalid_token"
+	openUnavailable   = "unavailable"
+	openError         = "error"
+)
+
 func (h *handler) openRedPacket(c *gin.Context) {
 	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -92,40 +637,469 @@ func (h *handler) openRedPacket(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	result, err := h.svc.OpenRedPacket(c.Request.Context(), campaignID, req.UserID)
+	claimID := logging.NewID()
+	ctx := logging.With(c.Request.Context(), logging.CampaignID(campaignID), logging.UserID(req.UserID), logging.ClaimID(claimID))
+	c.Request = c.Request.WithContext(ctx)
+	outcome := openError
+	defer func() { metrics.IncClaimOpen(campaignID, outcome) }()
+	status, err := h.waitingRoom.Admit(ctx, campaignID, req.UserID, c.GetHeader("X-Queue-Token"))
+	if err != nil {
+		if errors.Is(err, waitroom.ErrInvalidToken) {
+			outcome = openInvalidToken
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, waitroom.ErrNotAdmitted) {
+			outcome = openNotAdmitted
+			c.Header("Retry-After", strconv.FormatInt(etaSeconds(status.ETA), 10))
+			c.JSON(http.StatusTooManyRequests, gin.H{"status": "NOT_ADMITTED", "position": status.Position, "eta_seconds": etaSeconds(status.ETA)})
+			return
+		}
+		outcome = errorOutcome(err)
+		internalError(c, err)
+		return
+	}
+	// The Kafka slot is taken just before the claim script, so an open breaker
+	// turns the user away instead of losing a packet whose event cannot be
+	// published, while cached rejections never wait for Kafka.
+	var reservation *claim.Reservation
+	defer func() {
+		if reservation != nil {
+			reservation.Release()
+		}
+	}()
+	result, err := h.svc.OpenRedPacket(ctx, campaignID, req.UserID, func(ctx context.Context) error {
+		r, err := h.publisher.Reserve(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue claim: %w", err)
+		}
+		reservation = r
+		return nil
+	})
 	if err != nil {
 		if errors.Is(err, campaign.ErrCampaignNotFound) {
+			outcome = openNotFound
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
 		if errors.Is(err, campaign.ErrCampaignInactive) {
+			outcome = openInactive
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		outcome = errorOutcome(err)
+		internalError(c, err)
 		return
 	}
 
 	switch result.Status {
 	case campaign.StatusAlreadyOpened:
+		outcome = openAlreadyOpened
 		c.JSON(http.StatusConflict, gin.H{"status": result.Status})
 		return
 	case campaign.StatusSoldOut:
+		outcome = openSoldOut
 		c.JSON(http.StatusGone, gin.H{"status": result.Status})
 		return
 	case campaign.StatusOK:
 		event := campaign.ClaimEvent{
+			ClaimID:    claimID,
 			UserID:     req.UserID,
 			CampaignID: campaignID,
 			Amount:     result.Amount,
+			Currency:   result.Currency,
 			Timestamp:  time.Now().UTC(),
 		}
-		if err := h.publisher.Publish(c.Request.Context(), event); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enqueue claim"})
+		if err := reservation.Publish(event); err != nil {
+			outcome = errorOutcome(err)
+			internalError(c, fmt.Errorf("failed to enqueue claim: %w", err))
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"status": result.Status, "amount": result.Amount})
+		outcome = openOK
+		metrics.AddClaimedAmount(campaignID, result.Currency, result.Amount)
+		h.logger.LogAttrs(ctx, slog.LevelInfo, "red packet opened", slog.Int64("amount", result.Amount), slog.String("currency", result.Currency))
+		c.JSON(http.StatusOK, openRedPacketResponse{Status: result.Status, Amount: result.Amount, Currency: result.Currency})
 	default:
 		c.JSON(http.StatusServiceUnavailable, gin.H{"status": result.Status})
 	}
 }
+
+func (h *handler) joinWaitingRoom(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign id"})
+		return
+	}
+	var req joinWaitingRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	status, err := h.waitingRoom.Join(c.Request.Context(), campaignID, req.UserID)
+	if err != nil {
+		writeWaitingRoomError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newQueueStatusResponse(status))
+}
+
+func (h *handler) getQueueStatus(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign id"})
+		return
+	}
+	status, err := h.waitingRoom.Status(c.Request.Context(), campaignID, c.Query("token"))
+	if err != nil {
+		writeWaitingRoomError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newQueueStatusResponse(status))
+}
+
+func writeWaitingRoomError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, waitroom.ErrCampaignNotFound), errors.Is(err, waitroom.ErrNoWaitingRoom):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, waitroom.ErrInvalidToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		internalError(c, err)
+	}
+}
+
+func newQueueStatusResponse(s *waitroom.Status) queueStatusResponse {
+	return queueStatusResponse{Token: s.Token, Ticket: s.Ticket, Position: s.Position, Admitted: s.Admitted, ETASeconds: etaSeconds(s.ETA)}
+}
+
+// errorOutcome tells opens shed by a dependency guard apart from other failures.
+func errorOutcome(err error) string {
+	if errors.Is(err, resilience.ErrUnavailable) {
+		return openUnavailable
+	}
+	return openError
+}
+
+// internalError answers 503 with Retry-After when a dependency guard turned
+// the call away, and 500 otherwise. err is attached to the request's log line.
+func internalError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	var unavailable *resilience.UnavailableError
+	if errors.As(err, &unavailable) {
+		c.Header("Retry-After", strconv.FormatInt(max(etaSeconds(unavailable.RetryAfter), 1), 10))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+func listBreakers(guards []*resilience.Guard) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resp := make([]breakerResponse, 0, len(guards))
+		for _, g := range guards {
+			s := g.Snapshot()
+			resp = append(resp, breakerResponse{
+				Dependency:          s.Dependency,
+				State:               s.State.String(),
+				ConsecutiveFailures: s.ConsecutiveFailures,
+				InFlight:            s.InFlight,
+				MaxInFlight:         s.MaxInFlight,
+				OpenedAt:            s.OpenedAt,
+				RetryAfterSeconds:   s.RetryAfter.Seconds(),
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"breakers": resp})
+	}
+}
+
+// etaSeconds rounds a wait up to whole seconds, so clients never retry early.
+func etaSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+func (h *handler) getUserClaim(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign id"})
+		return
+	}
+	result, err := h.svc.GetUserClaim(c.Request.Context(), campaignID, c.Param("user_id"))
+	if err != nil {
+		if errors.Is(err, campaign.ErrClaimNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+	if result.Status == campaign.StatusClaimPending {
+		c.JSON(http.StatusAccepted, userClaimResponse{Status: result.Status})
+		return
+	}
+	c.JSON(http.StatusOK, userClaimResponse{Status: result.Status, Amount: result.Amount, Currency: result.Currency, ClaimedAt: &result.ClaimedAt})
+}
+
+func (h *handler) listUserClaims(c *gin.Context) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be an integer"})
+			return
+		}
+		limit = parsed
+	}
+	page, err := h.wallets.ListClaims(c.Request.Context(), c.Param("id"), c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, wallet.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+	resp := listUserClaimsResponse{Claims: make([]claimResponse, 0, len(page.Claims)), NextCursor: page.NextCursor}
+	for _, cl := range page.Claims {
+		resp.Claims = append(resp.Claims, claimResponse{
+			ID:         cl.ID,
+			CampaignID: cl.CampaignID,
+			Amount:     cl.Amount,
+			Currency:   cl.Currency,
+			ClaimedAt:  cl.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *handler) getUserWallet(c *gin.Context) {
+	userID := c.Param("id")
+	wallets, err := h.wallets.Balances(c.Request.Context(), userID)
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	resp := userWalletResponse{UserID: userID, Balances: make([]walletBalanceResponse, 0, len(wallets))}
+	for _, w := range wallets {
+		resp.Balances = append(resp.Balances, walletBalanceResponse{Currency: w.Currency, Balance: w.Balance, ClaimCount: w.ClaimCount})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *handler) getCampaignStats(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign id"})
+		return
+	}
+	query := analytics.Query{Step: time.Minute}
+	if raw := c.Query("bucket"); raw != "" {
+		if query.Step, err = time.ParseDuration(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be a duration such as 1m, 5m or 1h"})
+			return
+		}
+	}
+	for name, dst := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if raw := c.Query(name); raw != "" {
+			if *dst, err = time.Parse(time.RFC3339, raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an RFC3339 timestamp"})
+				return
+			}
+		}
+	}
+	report, err := h.analytics.Stats(c.Request.Context(), campaignID, query)
+	if err != nil {
+		if errors.Is(err, analytics.ErrCampaignNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, analytics.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, newCampaignStatsResponse(report))
+	case "csv":
+		writeCampaignStatsCSV(c, report)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+	}
+}
+
+func newCampaignStatsResponse(report *analytics.Report) campaignStatsResponse {
+	resp := campaignStatsResponse{
+		CampaignID:    report.Campaign.ID,
+		Currency:      report.Campaign.Currency,
+		BucketSeconds: int64(report.Step / time.Second),
+		TotalPackets:  report.TotalPackets,
+		Totals:        newStatsBucketResponse(report.Totals),
+		SoldOut: soldOutResponse{
+			P50Seconds:  durationSeconds(report.SoldOut.P50),
+			P95Seconds:  durationSeconds(report.SoldOut.P95),
+			P100Seconds: durationSeconds(report.SoldOut.P100),
+			Tiers:       make(map[int64]*float64, len(report.SoldOut.PerTier)),
+		},
+		Buckets: make([]statsBucketResponse, 0, len(report.Buckets)),
+	}
+	for amount, d := range report.SoldOut.PerTier {
+		resp.SoldOut.Tiers[amount] = durationSeconds(d)
+	}
+	for _, b := range report.Buckets {
+		resp.Buckets = append(resp.Buckets, newStatsBucketResponse(b))
+	}
+	return resp
+}
+
+func newStatsBucketResponse(b db.StatsBucket) statsBucketResponse {
+	var start *time.Time
+	if !b.Start.IsZero() {
+		start = &b.Start
+	}
+	return statsBucketResponse{Start: start, Claims: b.Claims, UniqueUsers: b.UniqueUsers, AmountSum: b.AmountSum, Tiers: b.TierClaims}
+}
+
+func durationSeconds(d *time.Duration) *float64 {
+	if d == nil {
+		return nil
+	}
+	seconds := d.Seconds()
+	return &seconds
+}
+
+// writeCampaignStatsCSV renders one row per bucket with a tier_<amount> column per inventory tier.
+func writeCampaignStatsCSV(c *gin.Context, report *analytics.Report) {
+	amounts := make([]int64, 0, len(report.Inventory))
+	for _, inv := range report.Inventory {
+		amounts = append(amounts, inv.Amount)
+	}
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i] < amounts[j] })
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename=campaign-"+strconv.FormatInt(report.Campaign.ID, 10)+"-stats.csv")
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	header := []string{"start", "claims", "unique_users", "amount_sum"}
+	for _, amount := range amounts {
+		header = append(header, "tier_"+strconv.FormatInt(amount, 10))
+	}
+	_ = w.Write(header)
+	for _, b := range report.Buckets {
+		row := []string{
+			b.Start.UTC().Format(time.RFC3339),
+			strconv.FormatInt(b.Claims, 10),
+			strconv.FormatInt(b.UniqueUsers, 10),
+			strconv.FormatInt(b.AmountSum, 10),
+		}
+		for _, amount := range amounts {
+			row = append(row, strconv.FormatInt(b.TierClaims[amount], 10))
+		}
+		_ = w.Write(row)
+	}
+	w.Flush()
+}
+
+func (h *handler) exportClaims(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign id"})
+		return
+	}
+	req := export.Request{CampaignID: campaignID, Format: c.DefaultQuery("format", "csv"), Metadata: true}
+	if raw := c.Query("gzip"); raw != "" {
+		if req.Gzip, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "gzip must be a boolean"})
+			return
+		}
+	}
+	if raw := c.Query("metadata"); raw != "" {
+		if req.Metadata, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "metadata must be a boolean"})
+			return
+		}
+	}
+	for name, dst := range map[string]*time.Time{"from": &req.From, "to": &req.To} {
+		if raw := c.Query(name); raw != "" {
+			if *dst, err = time.Parse(time.RFC3339, raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an RFC3339 timestamp"})
+				return
+			}
+		}
+	}
+	job, err := h.exporter.Prepare(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, export.ErrCampaignNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, export.ErrInvalidRequest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", job.ContentType())
+	c.Header("Content-Disposition", "attachment; filename="+job.Filename())
+	for name, value := range job.Headers() {
+		c.Header(name, value)
+	}
+	c.Header("Trailer", export.RowsTrailer)
+	c.Status(http.StatusOK)
+	// Headers are already sent, so a failure mid-stream can only truncate the
+	// body; the missing trailer tells the client the export is incomplete.
+	rows, err := job.Stream(c.Request.Context(), c.Writer)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Writer.Header().Set(export.RowsTrailer, strconv.FormatInt(rows, 10))
+}
+
+func (h *handler) getLeaderboard(c *gin.Context) {
+	campaignID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid campaign id"})
+		return
+	}
+	query := leaderboard.Query{UserID: c.Query("user_id")}
+	if raw := c.Query("limit"); raw != "" {
+		if query.Limit, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be an integer"})
+			return
+		}
+	}
+	if raw := c.Query("mask"); raw != "" {
+		if query.Mask, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mask must be a boolean"})
+			return
+		}
+	}
+	board, err := h.leaderboard.Top(c.Request.Context(), campaignID, query)
+	if err != nil {
+		if errors.Is(err, leaderboard.ErrCampaignNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		internalError(c, err)
+		return
+	}
+	resp := leaderboardResponse{
+		CampaignID: board.CampaignID,
+		Currency:   board.Currency,
+		Source:     board.Source,
+		Entries:    make([]leaderboardEntryResponse, 0, len(board.Entries)),
+	}
+	for _, e := range board.Entries {
+		resp.Entries = append(resp.Entries, leaderboardEntryResponse(e))
+	}
+	if board.Me != nil {
+		me := leaderboardEntryResponse(*board.Me)
+		resp.Me = &me
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -2,17 +2,29 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"redpacket/internal/app/api/config"
 	"redpacket/internal/app/api/router"
 	"redpacket/internal/db"
+	"redpacket/internal/domain/analytics"
 	"redpacket/internal/domain/campaign"
+	"redpacket/internal/domain/export"
+	"redpacket/internal/domain/leaderboard"
+	"redpacket/internal/domain/schedule"
+	"redpacket/internal/domain/waitroom"
+	"redpacket/internal/domain/wallet"
+	"redpacket/internal/health"
 	"redpacket/internal/kafka"
 	"redpacket/internal/messaging/claim"
+	"redpacket/internal/observability/logging"
+	"redpacket/internal/observability/tracing"
 	redispkg "redpacket/internal/redis"
+	"redpacket/internal/resilience"
 )
 
 // Server wires infrastructure dependencies for the API service.
@@ -22,47 +34,162 @@ type Server struct {
 	store      *db.Store
 	redis      *redispkg.Client
 	producer   *kafka.Producer
+	backlog    *kafka.Backlog
+	health     *health.Checker
+	scheduler  *schedule.Scheduler
+	warmer     *campaign.Warmer
+	stateCache *campaign.StateCache
+	inventory  *campaign.InventoryReporter
+	logger     *slog.Logger
+
+	shutdownTracing func(context.Context) error
 }
 
 // New constructs the server and underlying dependencies.
-func New(ctx context.Context, cfg config.Config) (*Server, error) {
+func New(ctx context.Context, cfg config.Config, logger *slog.Logger) (*Server, error) {
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName: "redpacket-api",
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	store, err := db.New(ctx, cfg.PostgresDSN)
 	if err != nil {
 		return nil, err
 	}
-	if err := store.EnsureSchema(context.Background()); err != nil {
+	if cfg.MigrateOnStart {
+		if err := store.Migrate(ctx); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+
+	guards := newGuards(cfg.Resilience, logger)
+	store.SetGuard(guards.postgres)
+
+	redisClient, err := redispkg.New(redispkg.Options{
+		Addrs:        cfg.Redis.Addrs,
+		MasterName:   cfg.Redis.MasterName,
+		Password:     cfg.Redis.Password,
+		Cluster:      cfg.Redis.Cluster,
+		BatchWindow:  cfg.Redis.BatchWindow,
+		BatchMaxSize: cfg.Redis.BatchMaxSize,
+		// A batch never outlives the deadline its commands get from the guard.
+		BatchTimeout: min(cfg.Redis.BatchTimeout, cfg.Resilience.Redis.Timeout),
+		Guard:        guards.redis,
+	})
+	if err != nil {
 		store.Close()
 		return nil, err
 	}
 
-	redisClient, err := redispkg.New(cfg.RedisAddr)
+	producer, err := kafka.NewProducer(cfg.KafkaBrokers, cfg.KafkaTopic)
 	if err != nil {
+		redisClient.Close()
 		store.Close()
 		return nil, err
 	}
+	producer.SetGuard(guards.kafka)
 
-	producer, err := kafka.NewProducer(cfg.KafkaBrokers, cfg.KafkaTopic)
+	claimBacklog, err := kafka.NewBacklog(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaGroup)
 	if err != nil {
+		producer.Close()
 		redisClient.Close()
 		store.Close()
 		return nil, err
 	}
 
-	svc := campaign.NewService(store, redisClient)
+	var stateCache *campaign.StateCache
+	if cfg.StateCache.Enabled {
+		stateCache = campaign.NewStateCache(redisClient, cfg.StateCache.TTL, logger)
+	}
+	svc := campaign.NewService(store, redisClient, cfg.Warmer.Lead, stateCache, logger)
 	publisher := claim.NewPublisher(producer)
+	checker := health.NewChecker(
+		health.Check{Name: "postgres", Run: store.Ping},
+		health.Check{Name: "redis", Run: redisClient.Ping},
+		health.Check{Name: "kafka", Run: producer.Ping},
+	)
+	secret := []byte(cfg.WaitingRoomSecret)
+	if len(secret) == 0 {
+		// Tokens from other replicas will not verify, so this only suits a
+		// single instance.
+		logger.Warn("WAITING_ROOM_SECRET is not set; using a random secret")
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+	}
+	waitingRoom := waitroom.NewService(store, redisClient, secret)
 	ginRouter := router.New(router.Dependencies{
-		CampaignService: svc,
-		Publisher:       publisher,
+		CampaignService:  svc,
+		WalletService:    wallet.NewService(store),
+		AnalyticsService: analytics.NewService(store),
+		Exporter:         export.NewExporter(store),
+		Leaderboard:      leaderboard.NewService(store, redisClient),
+		ScheduleService:  schedule.NewService(store, svc),
+		Publisher:        publisher,
+		WaitingRoom:      waitingRoom,
+		Guards:           []*resilience.Guard{guards.redis, guards.postgres, guards.kafka},
+		Health:           checker,
+		Logger:           logger,
 	})
 
 	httpSrv := &http.Server{Addr: ":" + cfg.Port, Handler: ginRouter}
-	return &Server{
-		cfg:        cfg,
-		httpServer: httpSrv,
-		store:      store,
-		redis:      redisClient,
-		producer:   producer,
-	}, nil
+	srv := &Server{
+		cfg:             cfg,
+		httpServer:      httpSrv,
+		store:           store,
+		redis:           redisClient,
+		producer:        producer,
+		backlog:         claimBacklog,
+		health:          checker,
+		stateCache:      stateCache,
+		logger:          logger,
+		shutdownTracing: shutdownTracing,
+		warmer: campaign.NewWarmer(svc, claimBacklog, campaign.WarmerConfig{
+			Interval:  cfg.Warmer.Interval,
+			BatchSize: cfg.Warmer.BatchSize,
+		}, logger),
+		inventory: campaign.NewInventoryReporter(svc, waitingRoom, campaign.InventoryReporterConfig{
+			Interval:     cfg.CampaignMetrics.Interval,
+			MaxCampaigns: cfg.CampaignMetrics.MaxCampaigns,
+		}, logger),
+	}
+	if cfg.Scheduler.Enabled {
+		srv.scheduler = schedule.NewScheduler(store, redisClient, svc, schedule.Config{
+			Interval:  cfg.Scheduler.Interval,
+			Lookahead: cfg.Scheduler.Lookahead,
+			LockTTL:   cfg.Scheduler.LockTTL,
+		}, logger)
+	}
+	return srv, nil
+}
+
+type dependencyGuards struct {
+	redis    *resilience.Guard
+	postgres *resilience.Guard
+	kafka    *resilience.Guard
+}
+
+func newGuards(cfg config.ResilienceConfig, logger *slog.Logger) dependencyGuards {
+	guard := func(name string, dep config.DependencyConfig) *resilience.Guard {
+		return resilience.NewGuard(resilience.Config{
+			Name:             name,
+			Timeout:          dep.Timeout,
+			MaxInFlight:      dep.MaxInFlight,
+			FailureThreshold: cfg.FailureThreshold,
+			Cooldown:         cfg.Cooldown,
+			Logger:           logger,
+		})
+	}
+	return dependencyGuards{
+		redis:    guard("redis", cfg.Redis),
+		postgres: guard("postgres", cfg.Postgres),
+		kafka:    guard("kafka", cfg.Kafka),
+	}
 }
 
 // Run starts the HTTP server and blocks until ctx is canceled or fatal error occurs.
@@ -73,9 +200,40 @@ func (s *Server) Run(ctx context.Context) error {
 			errCh <- err
 		}
 	}()
+	go func() {
+		if err := s.warmer.Run(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("campaign warmer stopped", logging.Err(err))
+		}
+	}()
+	go func() {
+		if err := s.inventory.Run(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("inventory reporter stopped", logging.Err(err))
+		}
+	}()
+	if s.stateCache != nil {
+		go func() {
+			if err := s.stateCache.Run(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("campaign state cache stopped", logging.Err(err))
+			}
+		}()
+	}
+	if s.scheduler != nil {
+		go func() {
+			if err := s.scheduler.Run(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("scheduler stopped", logging.Err(err))
+			}
+		}()
+	}
 
 	select {
 	case <-ctx.Done():
+		s.health.SetDraining()
+		s.logger.Info("api draining before shutdown", slog.Duration("drain_delay", s.cfg.DrainDelay))
+		select {
+		case <-time.After(s.cfg.DrainDelay):
+		case err := <-errCh:
+			return err
+		}
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		return s.httpServer.Shutdown(shutdownCtx)
@@ -87,6 +245,9 @@ func (s *Server) Run(ctx context.Context) error {
 // Close releases infrastructure resources.
 func (s *Server) Close() {
 	_ = s.httpServer.Close()
+	if s.backlog != nil {
+		_ = s.backlog.Close()
+	}
 	if s.producer != nil {
 		_ = s.producer.Close()
 	}
@@ -96,4 +257,9 @@ func (s *Server) Close() {
 	if s.store != nil {
 		s.store.Close()
 	}
+	if s.shutdownTracing != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.shutdownTracing(shutdownCtx)
+	}
 }
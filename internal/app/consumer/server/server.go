@@ -3,7 +3,8 @@ package server
 import (
 	"context"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -12,44 +13,158 @@ import (
 	consumerconfig "redpacket/internal/app/consumer/config"
 	"redpacket/internal/db"
 	"redpacket/internal/domain/campaign"
+	"redpacket/internal/domain/claimlog"
+	"redpacket/internal/domain/payout"
+	"redpacket/internal/domain/settlement"
+	"redpacket/internal/health"
+	"redpacket/internal/kafka"
 	"redpacket/internal/messaging/claim"
+	settlementmsg "redpacket/internal/messaging/settlement"
+	"redpacket/internal/observability/logging"
+	"redpacket/internal/observability/tracing"
+	"redpacket/internal/paymentprovider"
+	redispkg "redpacket/internal/redis"
 )
 
 // Server hosts the Kafka consumer workflow.
 type Server struct {
-	cfg      consumerconfig.Config
-	store    *db.Store
-	consumer *claim.Consumer
-	metrics  *http.Server
+	cfg         consumerconfig.Config
+	store       *db.Store
+	redis       *redispkg.Client
+	producer    *kafka.Producer
+	consumer    *claim.Consumer
+	backlog     *kafka.Backlog
+	payouts     *payout.Worker
+	settlements *settlement.Job
+	claimLogs   *claimlog.Maintainer
+	health      *health.Checker
+	metrics     *http.Server
+	logger      *slog.Logger
+
+	shutdownTracing func(context.Context) error
 }
 
 // New builds the consumer server and supporting dependencies.
-func New(ctx context.Context, cfg consumerconfig.Config) (*Server, error) {
+func New(ctx context.Context, cfg consumerconfig.Config, logger *slog.Logger) (*Server, error) {
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName: "redpacket-consumer",
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	store, err := db.New(ctx, cfg.PostgresDSN)
 	if err != nil {
 		return nil, err
 	}
-	if err := store.EnsureSchema(context.Background()); err != nil {
+	if cfg.MigrateOnStart {
+		if err := store.Migrate(ctx); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+
+	redisClient, err := redispkg.New(redispkg.Options{
+		Addrs:      cfg.Redis.Addrs,
+		MasterName: cfg.Redis.MasterName,
+		Password:   cfg.Redis.Password,
+		Cluster:    cfg.Redis.Cluster,
+	})
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	settlementProducer, err := kafka.NewProducer(cfg.KafkaBrokers, cfg.Settlement.Topic)
+	if err != nil {
+		redisClient.Close()
+		store.Close()
+		return nil, err
+	}
+
+	handler := campaign.NewClaimRecorder(store, logger)
+	claimConsumer, err := claim.NewConsumer(cfg.KafkaBrokers, cfg.KafkaGroup, cfg.KafkaTopic, handler, logger)
+	if err != nil {
+		settlementProducer.Close()
+		redisClient.Close()
 		store.Close()
 		return nil, err
 	}
 
-	handler := campaign.NewClaimRecorder(store)
-	claimConsumer, err := claim.NewConsumer(cfg.KafkaBrokers, cfg.KafkaGroup, cfg.KafkaTopic, handler)
+	claimBacklog, err := kafka.NewBacklog(cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaGroup)
 	if err != nil {
+		claimConsumer.Close()
+		settlementProducer.Close()
+		redisClient.Close()
 		store.Close()
 		return nil, err
 	}
 
+	settlementJob := settlement.NewJob(store, redisClient, settlementmsg.NewPublisher(settlementProducer), claimBacklog, settlement.Config{
+		Interval:   cfg.Settlement.Interval,
+		Delay:      cfg.Settlement.Delay,
+		RedisGrace: cfg.Settlement.RedisGrace,
+		BatchSize:  cfg.Settlement.BatchSize,
+	}, logger)
+
+	claimLogMaintainer := claimlog.NewMaintainer(store, claimlog.Config{
+		Interval:      cfg.ClaimLog.Interval,
+		PrecreateDays: cfg.ClaimLog.PrecreateDays,
+		RetentionDays: cfg.ClaimLog.RetentionDays,
+		ArchiveDir:    cfg.ClaimLog.ArchiveDir,
+	}, logger)
+
+	var payoutWorker *payout.Worker
+	if cfg.Payout.ProviderURL != "" {
+		payoutWorker = payout.NewWorker(store, paymentprovider.NewClient(cfg.Payout.ProviderURL), payout.WorkerConfig{
+			PollInterval: cfg.Payout.PollInterval,
+			BatchSize:    cfg.Payout.BatchSize,
+			MaxAttempts:  cfg.Payout.MaxAttempts,
+			BaseBackoff:  cfg.Payout.BaseBackoff,
+			MaxBackoff:   cfg.Payout.MaxBackoff,
+			CallTimeout:  cfg.Payout.CallTimeout,
+		}, logger)
+	}
+
+	checker := health.NewChecker(
+		health.Check{Name: "postgres", Run: store.Ping},
+		health.Check{Name: "redis", Run: redisClient.Ping},
+		health.Check{Name: "kafka", Run: claimConsumer.Ping},
+		health.Check{Name: "kafka_group", Run: func(context.Context) error {
+			status := claimConsumer.GroupStatus()
+			if !status.Member {
+				return errors.New("not a member of the consumer group")
+			}
+			if status.Lag > cfg.LagThreshold {
+				return fmt.Errorf("lag %d exceeds %d across %d partitions", status.Lag, cfg.LagThreshold, status.Partitions)
+			}
+			return nil
+		}},
+	)
+
 	metricsMux := http.NewServeMux()
 	metricsMux.Handle("/metrics", promhttp.Handler())
+	metricsMux.Handle("/healthz", checker.Liveness())
+	metricsMux.Handle("/readyz", checker.Readiness())
 	metricsSrv := &http.Server{Addr: cfg.MetricsAddr, Handler: metricsMux}
 
 	return &Server{
-		cfg:      cfg,
-		store:    store,
-		consumer: claimConsumer,
-		metrics:  metricsSrv,
+		cfg:             cfg,
+		store:           store,
+		redis:           redisClient,
+		producer:        settlementProducer,
+		consumer:        claimConsumer,
+		backlog:         claimBacklog,
+		payouts:         payoutWorker,
+		settlements:     settlementJob,
+		claimLogs:       claimLogMaintainer,
+		health:          checker,
+		metrics:         metricsSrv,
+		logger:          logger,
+		shutdownTracing: shutdownTracing,
 	}, nil
 }
 
@@ -58,11 +173,33 @@ func (s *Server) Run(ctx context.Context) error {
 	if s.metrics != nil {
 		go func() {
 			if err := s.metrics.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-				log.Printf("consumer metrics server stopped: %v", err)
+				s.logger.Error("consumer metrics server stopped", logging.Err(err))
 			}
 		}()
-		log.Printf("consumer metrics listening on %s", s.cfg.MetricsAddr)
+		s.logger.Info("consumer metrics listening", slog.String("addr", s.cfg.MetricsAddr))
 	}
+	if s.payouts != nil {
+		go func() {
+			if err := s.payouts.Run(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("payout worker stopped", logging.Err(err))
+			}
+		}()
+		s.logger.Info("payout worker started", slog.String("provider_url", s.cfg.Payout.ProviderURL))
+	}
+	go func() {
+		<-ctx.Done()
+		s.health.SetDraining()
+	}()
+	go func() {
+		if err := s.settlements.Run(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("settlement job stopped", logging.Err(err))
+		}
+	}()
+	go func() {
+		if err := s.claimLogs.Run(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("claim log maintainer stopped", logging.Err(err))
+		}
+	}()
 	return s.consumer.Start(ctx)
 }
 
@@ -76,7 +213,21 @@ func (s *Server) Close() {
 	if s.consumer != nil {
 		_ = s.consumer.Close()
 	}
+	if s.backlog != nil {
+		_ = s.backlog.Close()
+	}
+	if s.producer != nil {
+		_ = s.producer.Close()
+	}
+	if s.redis != nil {
+		_ = s.redis.Close()
+	}
 	if s.store != nil {
 		s.store.Close()
 	}
+	if s.shutdownTracing != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.shutdownTracing(shutdownCtx)
+	}
 }
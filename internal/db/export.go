@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// Claim log export formats.
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+)
+
+// ClaimLogFilter selects the claim_log rows of one campaign. Zero From/To leave that side open; To is exclusive.
+type ClaimLogFilter struct {
+	CampaignID int64
+	From       time.Time
+	To         time.Time
+}
+
+// CopyClaimLogs streams the matching rows ordered by id to w with COPY TO
+// STDOUT and returns the row count. CSV output starts with a header row.
+// NDJSON output is one JSON object per line; it uses CSV mode with control
+// characters as quote and delimiter, which JSON text always escapes, so the
+// lines are passed through verbatim.
+func (s *Store) CopyClaimLogs(ctx context.Context, filter ClaimLogFilter, format string, w io.Writer) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("copy_claim_logs", time.Since(start)) }()
+
+	// COPY takes no bind parameters, so the filter is rendered from typed values only.
+	where := []string{fmt.Sprintf("campaign_id = %d", filter.CampaignID)}
+	if !filter.From.IsZero() {
+		where = append(where, fmt.Sprintf("created_at >= '%s'", filter.From.UTC().Format(time.RFC3339Nano)))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, fmt.Sprintf("created_at < '%s'", filter.To.UTC().Format(time.RFC3339Nano)))
+	}
+	cond := strings.Join(where, " AND ")
+
+	var sql string
+	switch format {
+	case ExportCSV:
+		sql = fmt.Sprintf(`COPY (
+            SELECT id, user_id, campaign_id, amount, currency, created_at
+            FROM claim_log WHERE %s ORDER BY id
+        ) TO STDOUT WITH (FORMAT csv, HEADER true)`, cond)
+	case ExportNDJSON:
+		sql = fmt.Sprintf(`COPY (
+            SELECT json_build_object(
+                'record', 'claim', 'id', id, 'user_id', user_id, 'campaign_id', campaign_id,
+                'amount', amount, 'currency', currency, 'created_at', created_at
+            )::TEXT
+            FROM claim_log WHERE %s ORDER BY id
+        ) TO STDOUT WITH (FORMAT csv, QUOTE E'\x01', DELIMITER E'\x02')`, cond)
+	default:
+		return 0, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+	tag, err := conn.Conn().PgConn().CopyTo(ctx, w, sql)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// exec, query and queryRow run statements on the pool behind the store's
+// guard. Query results keep their guard slot and deadline until they are
+// closed or scanned.
+
+func (s *Store) exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	ctx, done, err := s.guard.Acquire(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	tag, err := s.pool.Exec(ctx, sql, args...)
+	done(isFailure(err))
+	return tag, err
+}
+
+func (s *Store) query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	ctx, done, err := s.guard.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.pool.Query(ctx, sql, args...)
+	if err != nil {
+		done(isFailure(err))
+		return nil, err
+	}
+	return &guardedRows{Rows: rows, done: done}, nil
+}
+
+func (s *Store) queryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	ctx, done, err := s.guard.Acquire(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return guardedRow{row: s.pool.QueryRow(ctx, sql, args...), done: done}
+}
+
+type guardedRows struct {
+	pgx.Rows
+	done func(failed bool)
+}
+
+func (r *guardedRows) Close() {
+	r.Rows.Close()
+	r.done(isFailure(r.Rows.Err()))
+}
+
+type guardedRow struct {
+	row  pgx.Row
+	done func(failed bool)
+}
+
+func (r guardedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	r.done(isFailure(err))
+	return err
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
+
+// isFailure tells Postgres being unreachable or slow apart from missing rows,
+// errors reported by the server, such as constraint violations, and callers
+// giving up.
+func isFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return err != nil && !errors.Is(err, pgx.ErrNoRows) && !errors.As(err, &pgErr) && !errors.Is(err, context.Canceled)
+}
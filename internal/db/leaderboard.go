@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// LeaderboardRow is one ranked winner of a campaign. Rank is 1-based.
+type LeaderboardRow struct {
+	Rank   int64
+	UserID string
+	Amount int64
+}
+
+// SnapshotLeaderboardTx stores the top size winners of a campaign from
+// claim_log. Ties are ordered by user id, descending, to match the live Redis
+// board. It returns the number of rows written.
+func (s *Store) SnapshotLeaderboardTx(ctx context.Context, tx pgx.Tx, campaignID int64, size int) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("snapshot_leaderboard", time.Since(start)) }()
+	cmdTag, err := tx.Exec(ctx, `
+        INSERT INTO campaign_leaderboard (campaign_id, rank, user_id, amount)
+        SELECT $1, ROW_NUMBER() OVER (ORDER BY amount DESC, user_id DESC), user_id, amount
+        FROM (
+            SELECT DISTINCT user_id, amount
+            FROM claim_log
+            WHERE campaign_id = $1
+            ORDER BY amount DESC, user_id DESC
+            LIMIT $2
+        ) top
+        ON CONFLICT (campaign_id, rank) DO NOTHING
+    `, campaignID, size)
+	if err != nil {
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), nil
+}
+
+// ListLeaderboardSnapshot returns the stored top limit winners of a campaign.
+func (s *Store) ListLeaderboardSnapshot(ctx context.Context, campaignID int64, limit int) ([]LeaderboardRow, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_leaderboard_snapshot", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT rank, user_id, amount
+        FROM campaign_leaderboard
+        WHERE campaign_id = $1
+        ORDER BY rank
+        LIMIT $2
+    `, campaignID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []LeaderboardRow
+	for rows.Next() {
+		var r LeaderboardRow
+		if err := rows.Scan(&r.Rank, &r.UserID, &r.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, r)
+	}
+	return items, rows.Err()
+}
+
+// GetClaimRank ranks the user's claim among every claim of the campaign with
+// the snapshot's ordering, or returns pgx.ErrNoRows if they did not claim.
+func (s *Store) GetClaimRank(ctx context.Context, campaignID int64, userID string) (*LeaderboardRow, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("get_claim_rank", time.Since(start)) }()
+	r := LeaderboardRow{UserID: userID}
+	if err := s.queryRow(ctx, `
+        WITH mine AS (
+            SELECT amount FROM claim_log
+            WHERE campaign_id = $1 AND user_id = $2
+            ORDER BY id
+            LIMIT 1
+        )
+        SELECT mine.amount, 1 + (
+            SELECT COUNT(DISTINCT c.user_id)
+            FROM claim_log c
+            WHERE c.campaign_id = $1
+              AND (c.amount > mine.amount OR (c.amount = mine.amount AND c.user_id > $2))
+        )
+        FROM mine
+    `, campaignID, userID).Scan(&r.Amount, &r.Rank); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// Ledger entry directions.
+const (
+	LedgerDebit  = "debit"
+	LedgerCredit = "credit"
+)
+
+// LedgerAccount identifies a ledger account by its unique code.
+type LedgerAccount struct {
+	Code       string
+	Kind       string
+	Currency   string
+	CampaignID *int64
+	UserID     *string
+}
+
+// LedgerTransfer describes a balanced two-legged ledger posting.
+type LedgerTransfer struct {
+	Kind       string
+	CampaignID int64
+	ClaimLogID *int64
+	Debit      LedgerAccount
+	Credit     LedgerAccount
+	Amount     int64
+	Currency   string
+}
+
+// CampaignLedgerBalance aggregates the figures needed to audit a campaign's escrow.
+type CampaignLedgerBalance struct {
+	CampaignID    int64
+	FundedTotal   int64
+	FundingPosted int64
+	EscrowBalance int64
+	ClaimedTotal  int64
+	OpenedTotal   int64
+}
+
+// PostLedgerTransferTx records a transfer as one ledger_txn with a debit and a credit entry.
+func (s *Store) PostLedgerTransferTx(ctx context.Context, tx pgx.Tx, transfer LedgerTransfer) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("post_ledger_transfer", time.Since(start)) }()
+	if transfer.Amount <= 0 {
+		return 0, errors.New("ledger transfer amount must be positive")
+	}
+	if transfer.Debit.Currency != transfer.Currency || transfer.Credit.Currency != transfer.Currency {
+		return 0, errors.New("ledger transfer accounts must share the transfer currency")
+	}
+	debitID, err := s.upsertLedgerAccountTx(ctx, tx, transfer.Debit)
+	if err != nil {
+		return 0, err
+	}
+	creditID, err := s.upsertLedgerAccountTx(ctx, tx, transfer.Credit)
+	if err != nil {
+		return 0, err
+	}
+	var txnID int64
+	if err := tx.QueryRow(ctx, `
+        INSERT INTO ledger_txn (kind, campaign_id, claim_log_id, currency)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id
+    `, transfer.Kind, transfer.CampaignID, transfer.ClaimLogID, transfer.Currency).Scan(&txnID); err != nil {
+		return 0, err
+	}
+	batch := &pgx.Batch{}
+	batch.Queue(`
+        INSERT INTO ledger_entry (txn_id, account_id, direction, amount)
+        VALUES ($1, $2, $3, $4)
+    `, txnID, debitID, LedgerDebit, transfer.Amount)
+	batch.Queue(`
+        INSERT INTO ledger_entry (txn_id, account_id, direction, amount)
+        VALUES ($1, $2, $3, $4)
+    `, txnID, creditID, LedgerCredit, transfer.Amount)
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, err
+	}
+	return txnID, nil
+}
+
+func (s *Store) upsertLedgerAccountTx(ctx context.Context, tx pgx.Tx, account LedgerAccount) (int64, error) {
+	var id int64
+	err := tx.QueryRow(ctx, `
+        INSERT INTO ledger_account (code, kind, currency, campaign_id, user_id)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (code) DO UPDATE SET code = EXCLUDED.code
+        RETURNING id
+    `, account.Code, account.Kind, account.Currency, account.CampaignID, account.UserID).Scan(&id)
+	return id, err
+}
+
+// ListCampaignLedgerBalances returns funded, escrow and claimed totals for
+// every campaign with an escrow account. Claimed totals are summed from the
+// claimTxnKind postings rather than claim_log, whose old partitions are
+// archived, and opened totals from campaign_inventory.
+func (s *Store) ListCampaignLedgerBalances(ctx context.Context, escrowKind, fundingTxnKind, claimTxnKind string) ([]CampaignLedgerBalance, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_campaign_ledger_balances", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        WITH escrow AS (
+            SELECT a.campaign_id,
+                   COALESCE(SUM(CASE WHEN e.direction = 'credit' THEN e.amount ELSE -e.amount END), 0)::BIGINT AS balance
+            FROM ledger_account a
+            LEFT JOIN ledger_entry e ON e.account_id = a.id
+            WHERE a.kind = $1
+            GROUP BY a.campaign_id
+        ),
+        funding AS (
+            SELECT t.campaign_id, SUM(e.amount)::BIGINT AS posted
+            FROM ledger_txn t
+            JOIN ledger_entry e ON e.txn_id = t.id AND e.direction = 'credit'
+            WHERE t.kind = $2
+            GROUP BY t.campaign_id
+        ),
+        funded AS (
+            SELECT campaign_id,
+                   SUM(amount * initial_total)::BIGINT AS total,
+                   SUM(amount * opened_count)::BIGINT AS opened
+            FROM campaign_inventory
+            GROUP BY campaign_id
+        ),
+        claimed AS (
+            SELECT t.campaign_id, SUM(e.amount)::BIGINT AS total
+            FROM ledger_txn t
+            JOIN ledger_entry e ON e.txn_id = t.id AND e.direction = 'credit'
+            WHERE t.kind = $3
+            GROUP BY t.campaign_id
+        )
+        SELECT escrow.campaign_id,
+               COALESCE(funded.total, 0),
+               COALESCE(funding.posted, 0),
+               escrow.balance,
+               COALESCE(claimed.total, 0),
+               COALESCE(funded.opened, 0)
+        FROM escrow
+        LEFT JOIN funded ON funded.campaign_id = escrow.campaign_id
+        LEFT JOIN funding ON funding.campaign_id = escrow.campaign_id
+        LEFT JOIN claimed ON claimed.campaign_id = escrow.campaign_id
+        ORDER BY escrow.campaign_id
+    `, escrowKind, fundingTxnKind, claimTxnKind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []CampaignLedgerBalance
+	for rows.Next() {
+		var b CampaignLedgerBalance
+		if err := rows.Scan(&b.CampaignID, &b.FundedTotal, &b.FundingPosted, &b.EscrowBalance, &b.ClaimedTotal, &b.OpenedTotal); err != nil {
+			return nil, err
+		}
+		items = append(items, b)
+	}
+	return items, rows.Err()
+}
+
+// ListUnbalancedLedgerTxns returns ids of ledger transactions whose debits and credits differ.
+func (s *Store) ListUnbalancedLedgerTxns(ctx context.Context) ([]int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_unbalanced_ledger_txns", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT txn_id
+        FROM ledger_entry
+        GROUP BY txn_id
+        HAVING SUM(CASE WHEN direction = 'debit' THEN amount ELSE 0 END)
+            <> SUM(CASE WHEN direction = 'credit' THEN amount ELSE 0 END)
+        ORDER BY txn_id
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/observability/metrics"
+)
+
+const claimLogDayLayout = "20060102"
+
+var claimLogPartitionRe = regexp.MustCompile(`^claim_log_([ph])(\d{8})$`)
+
+// ClaimLogPartition describes one claim_log partition. End is exclusive.
+type ClaimLogPartition struct {
+	Name     string
+	End      time.Time
+	Attached bool
+}
+
+// ClaimLogPartitionName returns the name of the partition holding the UTC day.
+func ClaimLogPartitionName(day time.Time) string {
+	return "claim_log_p" + day.UTC().Format(claimLogDayLayout)
+}
+
+// ListClaimLogPartitions returns the daily and history partitions, including
+// ones that were detached but not yet dropped.
+func (s *Store) ListClaimLogPartitions(ctx context.Context) ([]ClaimLogPartition, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_claim_log_partitions", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT c.relname, i.inhrelid IS NOT NULL
+        FROM pg_class c
+        LEFT JOIN pg_inherits i ON i.inhrelid = c.oid AND i.inhparent = 'claim_log'::regclass
+        WHERE c.relkind = 'r' AND c.relname ~ '^claim_log_[ph][0-9]{8}$'
+        ORDER BY c.relname
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []ClaimLogPartition
+	for rows.Next() {
+		var p ClaimLogPartition
+		if err := rows.Scan(&p.Name, &p.Attached); err != nil {
+			return nil, err
+		}
+		m := claimLogPartitionRe.FindStringSubmatch(p.Name)
+		if m == nil {
+			continue
+		}
+		day, err := time.Parse(claimLogDayLayout, m[2])
+		if err != nil {
+			return nil, err
+		}
+		if m[1] == "p" {
+			day = day.AddDate(0, 0, 1)
+		}
+		p.End = day
+		parts = append(parts, p)
+	}
+	return parts, rows.Err()
+}
+
+// CreateClaimLogPartition creates and attaches the partition for the UTC day.
+// Rows that already fell into claim_log_default for that day are moved into it
+// first, because attaching fails while the default partition overlaps.
+func (s *Store) CreateClaimLogPartition(ctx context.Context, day time.Time) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("create_claim_log_partition", time.Since(start)) }()
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+	name := pgx.Identifier{ClaimLogPartitionName(from)}.Sanitize()
+	return s.RunInTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`CREATE TABLE %s (LIKE claim_log INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, name)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`
+            WITH moved AS (
+                DELETE FROM claim_log_default
+                WHERE created_at >= $1 AND created_at < $2
+                RETURNING *
+            )
+            INSERT INTO %s SELECT * FROM moved
+        `, name), from, to); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE claim_log ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`,
+			name, from.Format(time.RFC3339), to.Format(time.RFC3339)))
+		return err
+	})
+}
+
+// ClaimLogPartitionHoldsUnsettled reports whether the partition has claims of
+// campaigns that are not settled yet.
+func (s *Store) ClaimLogPartitionHoldsUnsettled(ctx context.Context, name string) (bool, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("claim_log_partition_holds_unsettled", time.Since(start)) }()
+	var held bool
+	err := s.queryRow(ctx, fmt.Sprintf(`
+        SELECT EXISTS (
+            SELECT 1 FROM %s cl
+            WHERE NOT EXISTS (SELECT 1 FROM campaign_settlement cs WHERE cs.campaign_id = cl.campaign_id)
+        )
+    `, pgx.Identifier{name}.Sanitize())).Scan(&held)
+	return held, err
+}
+
+// DetachClaimLogPartition detaches a partition so it can be archived.
+func (s *Store) DetachClaimLogPartition(ctx context.Context, name string) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("detach_claim_log_partition", time.Since(start)) }()
+	_, err := s.exec(ctx, fmt.Sprintf(`ALTER TABLE claim_log DETACH PARTITION %s`, pgx.Identifier{name}.Sanitize()))
+	return err
+}
+
+// CopyClaimLogPartition streams a detached partition to w as CSV with a header row.
+func (s *Store) CopyClaimLogPartition(ctx context.Context, name string, w io.Writer) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("copy_claim_log_partition", time.Since(start)) }()
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	_, err = conn.Conn().PgConn().CopyTo(ctx, w, fmt.Sprintf(
+		`COPY (SELECT id, user_id, campaign_id, amount, currency, created_at FROM %s ORDER BY id) TO STDOUT WITH (FORMAT csv, HEADER true)`,
+		pgx.Identifier{name}.Sanitize()))
+	return err
+}
+
+// DropClaimLogPartition drops a detached partition.
+func (s *Store) DropClaimLogPartition(ctx context.Context, name string) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("drop_claim_log_partition", time.Since(start)) }()
+	_, err := s.exec(ctx, fmt.Sprintf(`DROP TABLE %s`, pgx.Identifier{name}.Sanitize()))
+	return err
+}
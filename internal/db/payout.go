@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// Payout states.
+const (
+	PayoutPending   = "pending"
+	PayoutRetrying  = "retrying"
+	PayoutSucceeded = "succeeded"
+	PayoutFailed    = "failed"
+)
+
+// Payout is read from the payout table.
+type Payout struct {
+	ID             int64
+	ClaimLogID     int64
+	CampaignID     int64
+	UserID         string
+	Amount         int64
+	Currency       string
+	IdempotencyKey string
+	Status         string
+	Attempts       int
+}
+
+// InsertPayoutTx enqueues a pending payout. Rows with an existing idempotency key are left untouched.
+func (s *Store) InsertPayoutTx(ctx context.Context, tx pgx.Tx, p Payout) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("insert_payout", time.Since(start)) }()
+	_, err := tx.Exec(ctx, `
+        INSERT INTO payout (claim_log_id, campaign_id, user_id, amount, currency, idempotency_key)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (idempotency_key) DO NOTHING
+    `, p.ClaimLogID, p.CampaignID, p.UserID, p.Amount, p.Currency, p.IdempotencyKey)
+	return err
+}
+
+// LeaseDuePayouts picks up to limit due payouts, counts an attempt and pushes
+// next_attempt_at out by lease so concurrent workers skip them meanwhile.
+func (s *Store) LeaseDuePayouts(ctx context.Context, limit int, lease time.Duration) ([]Payout, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("lease_due_payouts", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        UPDATE payout
+        SET attempts = attempts + 1,
+            next_attempt_at = NOW() + $2::BIGINT * INTERVAL '1 millisecond',
+            updated_at = NOW()
+        WHERE id IN (
+            SELECT id FROM payout
+            WHERE status IN ('pending', 'retrying') AND next_attempt_at <= NOW()
+            ORDER BY next_attempt_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, claim_log_id, campaign_id, user_id, amount, currency, idempotency_key, status, attempts
+    `, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Payout
+	for rows.Next() {
+		var p Payout
+		if err := rows.Scan(&p.ID, &p.ClaimLogID, &p.CampaignID, &p.UserID, &p.Amount, &p.Currency, &p.IdempotencyKey, &p.Status, &p.Attempts); err != nil {
+			return nil, err
+		}
+		items = append(items, p)
+	}
+	return items, rows.Err()
+}
+
+// MarkPayoutSucceeded records the provider reference of a completed payout.
+func (s *Store) MarkPayoutSucceeded(ctx context.Context, id int64, providerRef string) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("mark_payout_succeeded", time.Since(start)) }()
+	_, err := s.exec(ctx, `
+        UPDATE payout
+        SET status = 'succeeded', provider_ref = $2, last_error = NULL, updated_at = NOW()
+        WHERE id = $1
+    `, id, providerRef)
+	return err
+}
+
+// MarkPayoutRetrying schedules another attempt after delay.
+func (s *Store) MarkPayoutRetrying(ctx context.Context, id int64, delay time.Duration, lastErr string) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("mark_payout_retrying", time.Since(start)) }()
+	_, err := s.exec(ctx, `
+        UPDATE payout
+        SET status = 'retrying',
+            next_attempt_at = NOW() + $2::BIGINT * INTERVAL '1 millisecond',
+            last_error = $3,
+            updated_at = NOW()
+        WHERE id = $1
+    `, id, delay.Milliseconds(), lastErr)
+	return err
+}
+
+// MarkPayoutFailed gives up on a payout.
+func (s *Store) MarkPayoutFailed(ctx context.Context, id int64, lastErr string) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("mark_payout_failed", time.Since(start)) }()
+	_, err := s.exec(ctx, `
+        UPDATE payout
+        SET status = 'failed', last_error = $2, updated_at = NOW()
+        WHERE id = $1
+    `, id, lastErr)
+	return err
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// ErrOccurrenceExists indicates a schedule already created a campaign for the occurrence.
+var ErrOccurrenceExists = errors.New("schedule occurrence already created")
+
+// CampaignSchedule is read from the campaign_schedule table.
+type CampaignSchedule struct {
+	ID         int64
+	Name       string
+	TemplateID int64
+	Cron       string
+	Timezone   string
+	Enabled    bool
+	CreatedAt  time.Time
+}
+
+// isOccurrenceConflict reports whether err is the unique violation raised when
+// a schedule occurrence is inserted twice.
+func isOccurrenceConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "campaign_schedule_occurrence_key"
+}
+
+// InsertCampaignSchedule stores a schedule and returns its id.
+func (s *Store) InsertCampaignSchedule(ctx context.Context, sc CampaignSchedule) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("insert_campaign_schedule", time.Since(start)) }()
+	var id int64
+	if err := s.queryRow(ctx, `
+        INSERT INTO campaign_schedule (name, template_id, cron, timezone, enabled)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `, sc.Name, sc.TemplateID, sc.Cron, sc.Timezone, sc.Enabled).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetCampaignSchedule returns a schedule by id, or pgx.ErrNoRows when it does not exist.
+func (s *Store) GetCampaignSchedule(ctx context.Context, id int64) (*CampaignSchedule, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("get_campaign_schedule", time.Since(start)) }()
+	var sc CampaignSchedule
+	if err := s.queryRow(ctx, `
+        SELECT id, name, template_id, cron, timezone, enabled, created_at
+        FROM campaign_schedule
+        WHERE id = $1
+    `, id).Scan(&sc.ID, &sc.Name, &sc.TemplateID, &sc.Cron, &sc.Timezone, &sc.Enabled, &sc.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+// ListCampaignSchedules returns every schedule, or only enabled ones when enabledOnly is set.
+func (s *Store) ListCampaignSchedules(ctx context.Context, enabledOnly bool) ([]CampaignSchedule, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_campaign_schedules", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT id, name, template_id, cron, timezone, enabled, created_at
+        FROM campaign_schedule
+        WHERE enabled OR NOT $1
+        ORDER BY id
+    `, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []CampaignSchedule
+	for rows.Next() {
+		var sc CampaignSchedule
+		if err := rows.Scan(&sc.ID, &sc.Name, &sc.TemplateID, &sc.Cron, &sc.Timezone, &sc.Enabled, &sc.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, sc)
+	}
+	return items, rows.Err()
+}
+
+// SetCampaignScheduleEnabled pauses or resumes a schedule, returning pgx.ErrNoRows when it does not exist.
+func (s *Store) SetCampaignScheduleEnabled(ctx context.Context, id int64, enabled bool) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("set_campaign_schedule_enabled", time.Since(start)) }()
+	cmdTag, err := s.exec(ctx, `UPDATE campaign_schedule SET enabled = $2 WHERE id = $1`, id, enabled)
+	if err != nil {
+		return err
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ListScheduleOccurrences returns the occurrences the schedule has already
+// created campaigns for, from since onwards.
+func (s *Store) ListScheduleOccurrences(ctx context.Context, scheduleID int64, since time.Time) (map[time.Time]int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_schedule_occurrences", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT occurrence, id
+        FROM campaign
+        WHERE schedule_id = $1 AND occurrence >= $2
+    `, scheduleID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	created := make(map[time.Time]int64)
+	for rows.Next() {
+		var (
+			occurrence time.Time
+			id         int64
+		)
+		if err := rows.Scan(&occurrence, &id); err != nil {
+			return nil, err
+		}
+		created[occurrence.UTC()] = id
+	}
+	return created, rows.Err()
+}
@@ -1,5 +0,0 @@
-package db
-
-import "redpacket/migrations"
-
-var schemaSQL = migrations.Schema
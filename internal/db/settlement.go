@@ -0,0 +1,243 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// ErrAlreadySettled indicates a settlement row already exists for the campaign.
+var ErrAlreadySettled = errors.New("campaign already settled")
+
+// SettlementTier is the claimed versus unclaimed breakdown of one amount tier.
+type SettlementTier struct {
+	Amount         int64
+	InitialTotal   int
+	ClaimedCount   int
+	UnclaimedCount int
+}
+
+// Settlement is read from campaign_settlement and its tiers.
+type Settlement struct {
+	CampaignID       int64
+	Currency         string
+	FundedTotal      int64
+	ClaimedTotal     int64
+	UnclaimedTotal   int64
+	SettledAt        time.Time
+	EventPublishedAt *time.Time
+	RedisReleasedAt  *time.Time
+	Tiers            []SettlementTier
+}
+
+// ListCampaignsDueForSettlement returns unsettled campaigns that ended before endedBefore.
+func (s *Store) ListCampaignsDueForSettlement(ctx context.Context, endedBefore time.Time, limit int) ([]int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_campaigns_due_for_settlement", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT c.id
+        FROM campaign c
+        LEFT JOIN campaign_settlement cs ON cs.campaign_id = c.id
+        WHERE cs.campaign_id IS NULL
+          AND c.end_time < $1
+        ORDER BY c.end_time
+        LIMIT $2
+    `, endedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SettleCampaignTx freezes the campaign and writes its settlement from
+// campaign_inventory and claim_log. It returns ErrAlreadySettled if another
+// worker got there first.
+func (s *Store) SettleCampaignTx(ctx context.Context, tx pgx.Tx, campaignID int64) (*Settlement, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("settle_campaign", time.Since(start)) }()
+
+	settlement := &Settlement{CampaignID: campaignID}
+	if err := tx.QueryRow(ctx, `
+        UPDATE campaign SET frozen_at = COALESCE(frozen_at, NOW()), status = 'settled'
+        WHERE id = $1
+        RETURNING currency
+    `, campaignID).Scan(&settlement.Currency); err != nil {
+		return nil, err
+	}
+	var exists bool
+	if err := tx.QueryRow(ctx, `
+        SELECT EXISTS (SELECT 1 FROM campaign_settlement WHERE campaign_id = $1)
+    `, campaignID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrAlreadySettled
+	}
+
+	rows, err := tx.Query(ctx, `
+        SELECT i.amount, i.initial_total, COALESCE(c.claimed, 0)::INT
+        FROM campaign_inventory i
+        LEFT JOIN (
+            SELECT amount, COUNT(*) AS claimed
+            FROM claim_log
+            WHERE campaign_id = $1
+            GROUP BY amount
+        ) c ON c.amount = i.amount
+        WHERE i.campaign_id = $1
+        ORDER BY i.amount DESC
+    `, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var t SettlementTier
+		if err := rows.Scan(&t.Amount, &t.InitialTotal, &t.ClaimedCount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		t.UnclaimedCount = t.InitialTotal - t.ClaimedCount
+		if t.UnclaimedCount < 0 {
+			t.UnclaimedCount = 0
+		}
+		settlement.FundedTotal += t.Amount * int64(t.InitialTotal)
+		settlement.ClaimedTotal += t.Amount * int64(t.ClaimedCount)
+		settlement.Tiers = append(settlement.Tiers, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	settlement.UnclaimedTotal = settlement.FundedTotal - settlement.ClaimedTotal
+
+	if err := tx.QueryRow(ctx, `
+        INSERT INTO campaign_settlement (campaign_id, currency, funded_total, claimed_total, unclaimed_total)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING settled_at
+    `, campaignID, settlement.Currency, settlement.FundedTotal, settlement.ClaimedTotal, settlement.UnclaimedTotal).Scan(&settlement.SettledAt); err != nil {
+		return nil, err
+	}
+	batch := &pgx.Batch{}
+	for _, t := range settlement.Tiers {
+		batch.Queue(`
+            INSERT INTO campaign_settlement_tier (campaign_id, amount, initial_total, claimed_count, unclaimed_count)
+            VALUES ($1, $2, $3, $4, $5)
+        `, campaignID, t.Amount, t.InitialTotal, t.ClaimedCount, t.UnclaimedCount)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, err
+	}
+	return settlement, nil
+}
+
+// AdvanceCampaignStatuses moves scheduled campaigns to active and active ones
+// to ended as their window passes. Settled campaigns are left alone.
+func (s *Store) AdvanceCampaignStatuses(ctx context.Context) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("advance_campaign_statuses", time.Since(start)) }()
+	cmdTag, err := s.exec(ctx, `
+        UPDATE campaign
+        SET status = CASE WHEN end_time < NOW() THEN 'ended' ELSE 'active' END
+        WHERE (status = 'scheduled' AND start_time <= NOW())
+           OR (status = 'active' AND end_time < NOW())
+    `)
+	if err != nil {
+		return 0, err
+	}
+	return cmdTag.RowsAffected(), nil
+}
+
+// ListSettlementsPendingFollowUp returns settlements whose event or Redis release is outstanding.
+func (s *Store) ListSettlementsPendingFollowUp(ctx context.Context, limit int) ([]Settlement, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_settlements_pending_follow_up", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT campaign_id, currency, funded_total, claimed_total, unclaimed_total,
+               settled_at, event_published_at, redis_released_at
+        FROM campaign_settlement
+        WHERE event_published_at IS NULL OR redis_released_at IS NULL
+        ORDER BY settled_at
+        LIMIT $1
+    `, limit)
+	if err != nil {
+		return nil, err
+	}
+	var items []Settlement
+	for rows.Next() {
+		var st Settlement
+		if err := rows.Scan(&st.CampaignID, &st.Currency, &st.FundedTotal, &st.ClaimedTotal, &st.UnclaimedTotal,
+			&st.SettledAt, &st.EventPublishedAt, &st.RedisReleasedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		items = append(items, st)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range items {
+		tiers, err := s.listSettlementTiers(ctx, items[i].CampaignID)
+		if err != nil {
+			return nil, err
+		}
+		items[i].Tiers = tiers
+	}
+	return items, nil
+}
+
+func (s *Store) listSettlementTiers(ctx context.Context, campaignID int64) ([]SettlementTier, error) {
+	rows, err := s.query(ctx, `
+        SELECT amount, initial_total, claimed_count, unclaimed_count
+        FROM campaign_settlement_tier
+        WHERE campaign_id = $1
+        ORDER BY amount DESC
+    `, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []SettlementTier
+	for rows.Next() {
+		var t SettlementTier
+		if err := rows.Scan(&t.Amount, &t.InitialTotal, &t.ClaimedCount, &t.UnclaimedCount); err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, t)
+	}
+	return tiers, rows.Err()
+}
+
+// MarkSettlementEventPublished records that the settlement event was emitted.
+func (s *Store) MarkSettlementEventPublished(ctx context.Context, campaignID int64) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("mark_settlement_event_published", time.Since(start)) }()
+	_, err := s.exec(ctx, `
+        UPDATE campaign_settlement SET event_published_at = NOW() WHERE campaign_id = $1
+    `, campaignID)
+	return err
+}
+
+// MarkSettlementRedisReleased records that the campaign's Redis keys were scheduled for expiry.
+func (s *Store) MarkSettlementRedisReleased(ctx context.Context, campaignID int64) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("mark_settlement_redis_released", time.Since(start)) }()
+	_, err := s.exec(ctx, `
+        UPDATE campaign_settlement SET redis_released_at = NOW() WHERE campaign_id = $1
+    `, campaignID)
+	return err
+}
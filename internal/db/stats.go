@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// StatsBucket is one time bucket of a campaign's claim rollups.
+type StatsBucket struct {
+	Start       time.Time
+	Claims      int64
+	UniqueUsers int64
+	AmountSum   int64
+	TierClaims  map[int64]int64
+}
+
+// RecordClaimStatsTx adds a claim made at claimedAt to the per-minute rollups,
+// so they follow the claims themselves rather than when they were consumed.
+// The user is counted as unique only if claimLogID is their first claim in the
+// campaign.
+func (s *Store) RecordClaimStatsTx(ctx context.Context, tx pgx.Tx, campaignID, claimLogID int64, userID string, amount int64, claimedAt time.Time) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("record_claim_stats", time.Since(start)) }()
+	batch := &pgx.Batch{}
+	batch.Queue(`
+        INSERT INTO campaign_stats_minute (campaign_id, bucket, claims, unique_users, amount_sum)
+        VALUES ($1, date_trunc('minute', $5::TIMESTAMPTZ), 1,
+                CASE WHEN EXISTS (
+                    SELECT 1 FROM claim_log WHERE campaign_id = $1 AND user_id = $2 AND id <> $3
+                ) THEN 0 ELSE 1 END,
+                $4)
+        ON CONFLICT (campaign_id, bucket) DO UPDATE
+        SET claims = campaign_stats_minute.claims + 1,
+            unique_users = campaign_stats_minute.unique_users + EXCLUDED.unique_users,
+            amount_sum = campaign_stats_minute.amount_sum + EXCLUDED.amount_sum
+    `, campaignID, userID, claimLogID, amount, claimedAt)
+	batch.Queue(`
+        INSERT INTO campaign_stats_minute_tier (campaign_id, bucket, amount, claims)
+        VALUES ($1, date_trunc('minute', $3::TIMESTAMPTZ), $2, 1)
+        ON CONFLICT (campaign_id, bucket, amount) DO UPDATE
+        SET claims = campaign_stats_minute_tier.claims + 1
+    `, campaignID, amount, claimedAt)
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// ListCampaignStats aggregates the minute rollups into buckets of width step,
+// aligned to origin, for buckets starting in [from, to).
+func (s *Store) ListCampaignStats(ctx context.Context, campaignID int64, step time.Duration, origin, from, to time.Time) ([]StatsBucket, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_campaign_stats", time.Since(start)) }()
+	seconds := int64(step / time.Second)
+
+	rows, err := s.query(ctx, `
+        SELECT date_bin($2::BIGINT * INTERVAL '1 second', bucket, $3) AS b,
+               SUM(claims)::BIGINT, SUM(unique_users)::BIGINT, SUM(amount_sum)::BIGINT
+        FROM campaign_stats_minute
+        WHERE campaign_id = $1 AND bucket >= $4 AND bucket < $5
+        GROUP BY b
+        ORDER BY b
+    `, campaignID, seconds, origin, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var buckets []StatsBucket
+	index := make(map[time.Time]int)
+	for rows.Next() {
+		var b StatsBucket
+		if err := rows.Scan(&b.Start, &b.Claims, &b.UniqueUsers, &b.AmountSum); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		b.TierClaims = make(map[int64]int64)
+		index[b.Start.UTC()] = len(buckets)
+		buckets = append(buckets, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tierRows, err := s.query(ctx, `
+        SELECT date_bin($2::BIGINT * INTERVAL '1 second', bucket, $3) AS b, amount, SUM(claims)::BIGINT
+        FROM campaign_stats_minute_tier
+        WHERE campaign_id = $1 AND bucket >= $4 AND bucket < $5
+        GROUP BY b, amount
+    `, campaignID, seconds, origin, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer tierRows.Close()
+	for tierRows.Next() {
+		var (
+			bucketStart time.Time
+			amount      int64
+			claims      int64
+		)
+		if err := tierRows.Scan(&bucketStart, &amount, &claims); err != nil {
+			return nil, err
+		}
+		if i, ok := index[bucketStart.UTC()]; ok {
+			buckets[i].TierClaims[amount] = claims
+		}
+	}
+	return buckets, tierRows.Err()
+}
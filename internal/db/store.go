@@ -9,16 +9,66 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"redpacket/internal/observability/metrics"
+	"redpacket/internal/resilience"
+	"redpacket/migrations"
+)
+
+// Campaign lifecycle states stored in campaign.status.
+const (
+	CampaignScheduled = "scheduled"
+	CampaignActive    = "active"
+	CampaignEnded     = "ended"
+	CampaignSettled   = "settled"
 )
 
 // Store wraps a pgx connection pool and exposes typed helpers.
 type Store struct {
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	guard *resilience.Guard
+}
+
+// Campaign is read from the campaign table. Rules is an opaque JSON object.
+type Campaign struct {
+	ID         int64
+	Name       string
+	Currency   string
+	Status     string
+	Rules      []byte
+	TemplateID *int64
+	StartTime  time.Time
+	EndTime    time.Time
+	CreatedAt  time.Time
+	// Shards is the number of Redis shards holding the campaign's inventory.
+	Shards int
+	// ClaimMode is how the inventory is stored in Redis, counter or queue.
+	ClaimMode string
+	// AdmissionRate is how many users per second the waiting room admits,
+	// nil when the campaign has no waiting room.
+	AdmissionRate *int
+	// WarmedAt is when the campaign's Redis state was primed, nil until then.
+	WarmedAt *time.Time
+}
+
+// CampaignInput is used when inserting a campaign row. Empty Rules store "{}",
+// zero Shards stores 1 and an empty ClaimMode stores counter. ScheduleID and Occurrence are set for campaigns
+// created by a schedule.
+type CampaignInput struct {
+	Name          string
+	Currency      string
+	Rules         []byte
+	TemplateID    *int64
+	ScheduleID    *int64
+	Occurrence    *time.Time
+	Shards        int
+	ClaimMode     string
+	AdmissionRate *int
+	StartTime     time.Time
+	EndTime       time.Time
 }
 
 // CampaignInventoryInput is used when seeding campaign inventory rows.
 type CampaignInventoryInput struct {
-	Amount int
+	Amount int64
 	Count  int
 }
 
@@ -26,16 +76,20 @@ type CampaignInventoryInput struct {
 type CampaignInventory struct {
 	ID           int64
 	CampaignID   int64
-	Amount       int
+	Amount       int64
+	Currency     string
 	InitialTotal int
 	OpenedCount  int
 }
 
-// ClaimLog holds data for claim_log insertions.
+// ClaimLog holds data for claim_log insertions and reads.
 type ClaimLog struct {
+	ID         int64
 	UserID     string
 	CampaignID int64
-	Amount     int
+	Amount     int64
+	Currency   string
+	CreatedAt  time.Time
 }
 
 // New creates a Store backed by a pgx pool.
@@ -44,6 +98,7 @@ func New(ctx context.Context, dsn string) (*Store, error) {
 	if err != nil {
 		return nil, err
 	}
+	cfg.ConnConfig.Tracer = queryTracer{}
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, err
@@ -51,6 +106,12 @@ func New(ctx context.Context, dsn string) (*Store, error) {
 	return &Store{pool: pool}, nil
 }
 
+// SetGuard puts queries and transactions behind g. It must be called before
+// the store is used. Migrations and COPY exports are not guarded.
+func (s *Store) SetGuard(g *resilience.Guard) {
+	s.guard = g
+}
+
 // Close releases underlying connections.
 func (s *Store) Close() {
 	s.pool.Close()
@@ -61,57 +122,116 @@ func (s *Store) Ping(ctx context.Context) error {
 	return s.pool.Ping(ctx)
 }
 
-// EnsureSchema guarantees required tables exist.
-func (s *Store) EnsureSchema(ctx context.Context) error {
-	start := time.Now()
-	defer metrics.ObserveDBOperation("ensure_schema", time.Since(start))
-	_, err := s.pool.Exec(ctx, schemaSQL)
+// Migrate applies every pending migration embedded in the binary.
+func (s *Store) Migrate(ctx context.Context) error {
+	all, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		return err
+	}
+	_, err = s.MigrateUp(ctx, all)
 	return err
 }
 
 // RunInTx executes fn within a transaction boundary.
 func (s *Store) RunInTx(ctx context.Context, fn func(pgx.Tx) error) error {
 	start := time.Now()
-	defer metrics.ObserveDBOperation("run_in_tx", time.Since(start))
+	defer func() { metrics.ObserveDBOperation("run_in_tx", time.Since(start)) }()
+	ctx, done, err := s.guard.Acquire(ctx)
+	if err != nil {
+		return err
+	}
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
+		done(isFailure(err))
 		return err
 	}
 	if err := fn(tx); err != nil {
 		_ = tx.Rollback(ctx)
+		// fn may fail on other dependencies, so its errors are not held
+		// against Postgres.
+		done(false)
 		return err
 	}
-	return tx.Commit(ctx)
+	err = tx.Commit(ctx)
+	done(isFailure(err))
+	return err
 }
 
-// InsertCampaignTx inserts a campaign row inside an existing transaction.
-func (s *Store) InsertCampaignTx(ctx context.Context, tx pgx.Tx, name string, startTime, endTime time.Time) (int64, error) {
+// InsertCampaignTx inserts a campaign row inside an existing transaction. It
+// returns ErrOccurrenceExists if the schedule occurrence was already created.
+func (s *Store) InsertCampaignTx(ctx context.Context, tx pgx.Tx, in CampaignInput) (int64, error) {
 	start := time.Now()
-	defer metrics.ObserveDBOperation("insert_campaign", time.Since(start))
+	defer func() { metrics.ObserveDBOperation("insert_campaign", time.Since(start)) }()
+	rules := in.Rules
+	if len(rules) == 0 {
+		rules = []byte("{}")
+	}
+	shards := in.Shards
+	if shards == 0 {
+		shards = 1
+	}
+	mode := in.ClaimMode
+	if mode == "" {
+		mode = "counter"
+	}
 	var id int64
 	if err := tx.QueryRow(ctx, `
-        INSERT INTO campaign (name, start_time, end_time, created_at)
-        VALUES ($1, $2, $3, NOW())
+        INSERT INTO campaign (name, currency, rules, template_id, schedule_id, occurrence, inventory_shards, claim_mode, admission_rate, start_time, end_time, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
         RETURNING id
-    `, name, startTime, endTime).Scan(&id); err != nil {
+    `, in.Name, in.Currency, string(rules), in.TemplateID, in.ScheduleID, in.Occurrence, shards, mode, in.AdmissionRate, in.StartTime, in.EndTime).Scan(&id); err != nil {
+		if isOccurrenceConflict(err) {
+			return 0, ErrOccurrenceExists
+		}
 		return 0, err
 	}
 	return id, nil
 }
 
-// InsertCampaignInventoryTx seeds campaign inventory rows within a tx.
-func (s *Store) InsertCampaignInventoryTx(ctx context.Context, tx pgx.Tx, campaignID int64, inventory []CampaignInventoryInput) error {
+// GetCampaign returns a campaign by id, or pgx.ErrNoRows when it does not exist.
+func (s *Store) GetCampaign(ctx context.Context, id int64) (*Campaign, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("get_campaign", time.Since(start)) }()
+	return scanCampaign(s.queryRow(ctx, `
+        SELECT `+campaignColumns+`
+        FROM campaign
+        WHERE id = $1
+    `, id))
+}
+
+// rowScanner is satisfied by pgx.Row and pgx.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+const campaignColumns = `id, name, currency, status, rules::TEXT, template_id, start_time, end_time, created_at, inventory_shards, claim_mode, admission_rate, warmed_at`
+
+func scanCampaign(row rowScanner) (*Campaign, error) {
+	var (
+		c     Campaign
+		rules string
+	)
+	if err := row.Scan(&c.ID, &c.Name, &c.Currency, &c.Status, &rules, &c.TemplateID, &c.StartTime, &c.EndTime, &c.CreatedAt, &c.Shards, &c.ClaimMode, &c.AdmissionRate, &c.WarmedAt); err != nil {
+		return nil, err
+	}
+	c.Rules = []byte(rules)
+	return &c, nil
+}
+
+// InsertCampaignInventoryTx seeds campaign inventory rows within a tx. Every
+// tier is stored in the campaign's single currency.
+func (s *Store) InsertCampaignInventoryTx(ctx context.Context, tx pgx.Tx, campaignID int64, currency string, inventory []CampaignInventoryInput) error {
 	start := time.Now()
-	defer metrics.ObserveDBOperation("insert_campaign_inventory", time.Since(start))
+	defer func() { metrics.ObserveDBOperation("insert_campaign_inventory", time.Since(start)) }()
 	if len(inventory) == 0 {
 		return errors.New("inventory required")
 	}
 	batch := &pgx.Batch{}
 	for _, inv := range inventory {
 		batch.Queue(`
-            INSERT INTO campaign_inventory (campaign_id, amount, initial_total)
-            VALUES ($1, $2, $3)
-        `, campaignID, inv.Amount, inv.Count)
+            INSERT INTO campaign_inventory (campaign_id, amount, currency, initial_total)
+            VALUES ($1, $2, $3, $4)
+        `, campaignID, inv.Amount, currency, inv.Count)
 	}
 	br := tx.SendBatch(ctx, batch)
 	return br.Close()
@@ -120,9 +240,9 @@ func (s *Store) InsertCampaignInventoryTx(ctx context.Context, tx pgx.Tx, campai
 // ListCampaignInventory fetches per-amount inventory rows.
 func (s *Store) ListCampaignInventory(ctx context.Context, campaignID int64) ([]CampaignInventory, error) {
 	start := time.Now()
-	defer metrics.ObserveDBOperation("list_campaign_inventory", time.Since(start))
-	rows, err := s.pool.Query(ctx, `
-        SELECT id, campaign_id, amount, initial_total, opened_count
+	defer func() { metrics.ObserveDBOperation("list_campaign_inventory", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT id, campaign_id, amount, currency, initial_total, opened_count
         FROM campaign_inventory
         WHERE campaign_id = $1
         ORDER BY amount DESC
@@ -135,7 +255,7 @@ func (s *Store) ListCampaignInventory(ctx context.Context, campaignID int64) ([]
 	var items []CampaignInventory
 	for rows.Next() {
 		var inv CampaignInventory
-		if err := rows.Scan(&inv.ID, &inv.CampaignID, &inv.Amount, &inv.InitialTotal, &inv.OpenedCount); err != nil {
+		if err := rows.Scan(&inv.ID, &inv.CampaignID, &inv.Amount, &inv.Currency, &inv.InitialTotal, &inv.OpenedCount); err != nil {
 			return nil, err
 		}
 		items = append(items, inv)
@@ -143,26 +263,50 @@ func (s *Store) ListCampaignInventory(ctx context.Context, campaignID int64) ([]
 	return items, rows.Err()
 }
 
-// InsertClaimLog stores a claim event for auditing.
-func (s *Store) InsertClaimLog(ctx context.Context, logEntry ClaimLog) error {
+// InsertClaimDedupeTx records that the user's claim on the campaign is being
+// stored inside an existing transaction. It reports false when the claim was
+// already recorded, in which case the caller must write nothing else.
+func (s *Store) InsertClaimDedupeTx(ctx context.Context, tx pgx.Tx, campaignID int64, userID string) (bool, error) {
 	start := time.Now()
-	defer metrics.ObserveDBOperation("insert_claim_log", time.Since(start))
-	_, err := s.pool.Exec(ctx, `
-        INSERT INTO claim_log (user_id, campaign_id, amount)
-        VALUES ($1, $2, $3)
-    `, logEntry.UserID, logEntry.CampaignID, logEntry.Amount)
-	return err
+	defer func() { metrics.ObserveDBOperation("insert_claim_dedupe", time.Since(start)) }()
+	cmdTag, err := tx.Exec(ctx, `
+        INSERT INTO claim_dedupe (campaign_id, user_id)
+        VALUES ($1, $2)
+        ON CONFLICT (campaign_id, user_id) DO NOTHING
+    `, campaignID, userID)
+	if err != nil {
+		return false, err
+	}
+	return cmdTag.RowsAffected() == 1, nil
+}
+
+// InsertClaimLogTx stores a claim event for auditing inside an existing
+// transaction, dated logEntry.CreatedAt.
+func (s *Store) InsertClaimLogTx(ctx context.Context, tx pgx.Tx, logEntry ClaimLog) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("insert_claim_log", time.Since(start)) }()
+	var id int64
+	if err := tx.QueryRow(ctx, `
+        INSERT INTO claim_log (user_id, campaign_id, amount, currency, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `, logEntry.UserID, logEntry.CampaignID, logEntry.Amount, logEntry.Currency, logEntry.CreatedAt).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
 }
 
-// IncrementOpenedCount bumps opened_count for the claimed amount.
-func (s *Store) IncrementOpenedCount(ctx context.Context, campaignID int64, amount int) error {
+// IncrementOpenedCountTx bumps opened_count for the claimed amount inside an
+// existing transaction. A currency that differs from the tier's is treated as
+// a missing row.
+func (s *Store) IncrementOpenedCountTx(ctx context.Context, tx pgx.Tx, campaignID, amount int64, currency string) error {
 	start := time.Now()
-	defer metrics.ObserveDBOperation("increment_opened_count", time.Since(start))
-	cmdTag, err := s.pool.Exec(ctx, `
+	defer func() { metrics.ObserveDBOperation("increment_opened_count", time.Since(start)) }()
+	cmdTag, err := tx.Exec(ctx, `
         UPDATE campaign_inventory
         SET opened_count = opened_count + 1
-        WHERE campaign_id = $1 AND amount = $2
-    `, campaignID, amount)
+        WHERE campaign_id = $1 AND amount = $2 AND currency = $3
+    `, campaignID, amount, currency)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// CampaignTemplate is read from the campaign_template table. Inventory maps
+// amounts in minor units of Currency to packet counts; Rules is an opaque JSON
+// object copied onto every campaign created from the template.
+type CampaignTemplate struct {
+	ID          int64
+	Name        string
+	NamePattern string
+	Currency    string
+	Inventory   map[int64]int
+	Rules       []byte
+	Duration    time.Duration
+	CreatedAt   time.Time
+}
+
+// InsertCampaignTemplate stores a template and returns its id.
+func (s *Store) InsertCampaignTemplate(ctx context.Context, t CampaignTemplate) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("insert_campaign_template", time.Since(start)) }()
+	inventory := make(map[string]int, len(t.Inventory))
+	for amount, count := range t.Inventory {
+		inventory[strconv.FormatInt(amount, 10)] = count
+	}
+	inventoryJSON, err := json.Marshal(inventory)
+	if err != nil {
+		return 0, err
+	}
+	rules := t.Rules
+	if len(rules) == 0 {
+		rules = []byte("{}")
+	}
+	var id int64
+	if err := s.queryRow(ctx, `
+        INSERT INTO campaign_template (name, name_pattern, currency, inventory, rules, duration_seconds)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `, t.Name, t.NamePattern, t.Currency, string(inventoryJSON), string(rules), int64(t.Duration/time.Second)).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetCampaignTemplate returns a template by id, or pgx.ErrNoRows when it does not exist.
+func (s *Store) GetCampaignTemplate(ctx context.Context, id int64) (*CampaignTemplate, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("get_campaign_template", time.Since(start)) }()
+	row := s.queryRow(ctx, `
+        SELECT id, name, name_pattern, currency, inventory::TEXT, rules::TEXT, duration_seconds, created_at
+        FROM campaign_template
+        WHERE id = $1
+    `, id)
+	return scanCampaignTemplate(row)
+}
+
+// ListCampaignTemplates returns every template ordered by name.
+func (s *Store) ListCampaignTemplates(ctx context.Context) ([]CampaignTemplate, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_campaign_templates", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT id, name, name_pattern, currency, inventory::TEXT, rules::TEXT, duration_seconds, created_at
+        FROM campaign_template
+        ORDER BY name
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []CampaignTemplate
+	for rows.Next() {
+		t, err := scanCampaignTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *t)
+	}
+	return items, rows.Err()
+}
+
+func scanCampaignTemplate(row rowScanner) (*CampaignTemplate, error) {
+	var (
+		t               CampaignTemplate
+		inventoryJSON   string
+		rules           string
+		durationSeconds int64
+	)
+	if err := row.Scan(&t.ID, &t.Name, &t.NamePattern, &t.Currency, &inventoryJSON, &rules, &durationSeconds, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	var inventory map[string]int
+	if err := json.Unmarshal([]byte(inventoryJSON), &inventory); err != nil {
+		return nil, err
+	}
+	t.Inventory = make(map[int64]int, len(inventory))
+	for raw, count := range inventory {
+		amount, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		t.Inventory[amount] = count
+	}
+	t.Rules = []byte(rules)
+	t.Duration = time.Duration(durationSeconds) * time.Second
+	return &t, nil
+}
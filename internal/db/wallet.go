@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// UserWallet is read from the user_wallet table.
+type UserWallet struct {
+	UserID     string
+	Currency   string
+	Balance    int64
+	ClaimCount int
+	UpdatedAt  time.Time
+}
+
+// CreditUserWalletTx adds a claimed amount to the user's balance in the given
+// currency. It is not idempotent: call it only in a transaction where
+// InsertClaimDedupeTx reported the claim as new.
+func (s *Store) CreditUserWalletTx(ctx context.Context, tx pgx.Tx, userID, currency string, amount int64) error {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("credit_user_wallet", time.Since(start)) }()
+	_, err := tx.Exec(ctx, `
+        INSERT INTO user_wallet (user_id, currency, balance, claim_count, updated_at)
+        VALUES ($1, $2, $3, 1, NOW())
+        ON CONFLICT (user_id, currency) DO UPDATE
+        SET balance = user_wallet.balance + EXCLUDED.balance,
+            claim_count = user_wallet.claim_count + 1,
+            updated_at = NOW()
+    `, userID, currency, amount)
+	return err
+}
+
+// ListUserWallets returns the user's balance per currency.
+func (s *Store) ListUserWallets(ctx context.Context, userID string) ([]UserWallet, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_user_wallets", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT user_id, currency, balance, claim_count, updated_at
+        FROM user_wallet
+        WHERE user_id = $1
+        ORDER BY currency
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []UserWallet
+	for rows.Next() {
+		var w UserWallet
+		if err := rows.Scan(&w.UserID, &w.Currency, &w.Balance, &w.ClaimCount, &w.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, w)
+	}
+	return items, rows.Err()
+}
+
+// ListClaimLogsByUser pages through a user's claims newest first, starting below beforeID when it is positive.
+func (s *Store) ListClaimLogsByUser(ctx context.Context, userID string, beforeID int64, limit int) ([]ClaimLog, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_claim_logs_by_user", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT id, user_id, campaign_id, amount, currency, created_at
+        FROM claim_log
+        WHERE user_id = $1 AND ($2 <= 0 OR id < $2)
+        ORDER BY id DESC
+        LIMIT $3
+    `, userID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ClaimLog
+	for rows.Next() {
+		var l ClaimLog
+		if err := rows.Scan(&l.ID, &l.UserID, &l.CampaignID, &l.Amount, &l.Currency, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, l)
+	}
+	return items, rows.Err()
+}
+
+// GetClaimLog returns the user's claim for a campaign, or pgx.ErrNoRows when none was persisted.
+func (s *Store) GetClaimLog(ctx context.Context, campaignID int64, userID string) (*ClaimLog, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("get_claim_log", time.Since(start)) }()
+	var l ClaimLog
+	if err := s.queryRow(ctx, `
+        SELECT id, user_id, campaign_id, amount, currency, created_at
+        FROM claim_log
+        WHERE campaign_id = $1 AND user_id = $2
+        ORDER BY id
+        LIMIT 1
+    `, campaignID, userID).Scan(&l.ID, &l.UserID, &l.CampaignID, &l.Amount, &l.Currency, &l.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
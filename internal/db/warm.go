@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// ListCampaignsDueForWarming returns unwarmed, unfinished campaigns that start within lead.
+func (s *Store) ListCampaignsDueForWarming(ctx context.Context, lead time.Duration, limit int) ([]int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_campaigns_due_for_warming", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT id
+        FROM campaign
+        WHERE warmed_at IS NULL
+          AND status IN ('scheduled', 'active')
+          AND start_time <= NOW() + $1::BIGINT * INTERVAL '1 millisecond'
+          AND end_time > NOW()
+        ORDER BY start_time
+        LIMIT $2
+    `, lead.Milliseconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MarkCampaignWarmed records that the campaign's Redis state was primed. It
+// reports false if warmed_at was already set.
+func (s *Store) MarkCampaignWarmed(ctx context.Context, campaignID int64) (bool, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("mark_campaign_warmed", time.Since(start)) }()
+	cmdTag, err := s.exec(ctx, `UPDATE campaign SET warmed_at = NOW() WHERE id = $1 AND warmed_at IS NULL`, campaignID)
+	if err != nil {
+		return false, err
+	}
+	return cmdTag.RowsAffected() == 1, nil
+}
+
+// ListWarmedLiveCampaigns returns up to limit warmed, unsettled campaigns
+// that have not ended and whose id is above afterID, by id.
+func (s *Store) ListWarmedLiveCampaigns(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_warmed_live_campaigns", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT id
+        FROM campaign
+        WHERE warmed_at IS NOT NULL
+          AND status IN ('scheduled', 'active')
+          AND end_time > NOW()
+          AND id > $1
+        ORDER BY id
+        LIMIT $2
+    `, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ListCampaignWinners returns the amount each user claimed on the campaign,
+// as recorded so far.
+func (s *Store) ListCampaignWinners(ctx context.Context, campaignID int64) (map[string]int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_campaign_winners", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT user_id, amount
+        FROM claim_log
+        WHERE campaign_id = $1
+    `, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	winners := make(map[string]int64)
+	for rows.Next() {
+		var (
+			userID string
+			amount int64
+		)
+		if err := rows.Scan(&userID, &amount); err != nil {
+			return nil, err
+		}
+		winners[userID] = amount
+	}
+	return winners, rows.Err()
+}
+
+// CountStartedUnwarmedCampaigns counts campaigns inside their window that were never primed.
+func (s *Store) CountStartedUnwarmedCampaigns(ctx context.Context) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("count_started_unwarmed_campaigns", time.Since(start)) }()
+	var n int64
+	err := s.queryRow(ctx, `
+        SELECT COUNT(*)
+        FROM campaign
+        WHERE warmed_at IS NULL AND start_time <= NOW() AND end_time > NOW() AND status <> 'settled'
+    `).Scan(&n)
+	return n, err
+}
+
+// ListLiveCampaigns returns up to limit warmed campaigns that have not ended,
+// earliest start first.
+func (s *Store) ListLiveCampaigns(ctx context.Context, limit int) ([]int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveDBOperation("list_live_campaigns", time.Since(start)) }()
+	rows, err := s.query(ctx, `
+        SELECT id
+        FROM campaign
+        WHERE warmed_at IS NOT NULL
+          AND status IN ('scheduled', 'active')
+          AND end_time > NOW()
+        ORDER BY start_time, id
+        LIMIT $1
+    `, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
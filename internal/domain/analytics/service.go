@@ -0,0 +1,162 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/db"
+)
+
+var (
+	// ErrCampaignNotFound indicates the campaign is missing.
+	ErrCampaignNotFound = errors.New("campaign not found")
+	// ErrInvalidQuery indicates an unusable bucket width or time range.
+	ErrInvalidQuery = errors.New("invalid stats query")
+)
+
+// Query selects the buckets of a stats report. Zero From/To default to the campaign window.
+type Query struct {
+	Step time.Duration
+	From time.Time
+	To   time.Time
+}
+
+// SoldOut captures how long after start_time a share of the campaign's packets was gone.
+// Nil values mean the threshold has not been reached.
+type SoldOut struct {
+	P50     *time.Duration
+	P95     *time.Duration
+	P100    *time.Duration
+	PerTier map[int64]*time.Duration
+}
+
+// Report is a campaign's claim timeseries with totals and sell-out timings.
+type Report struct {
+	Campaign     db.Campaign
+	Inventory    []db.CampaignInventory
+	Step         time.Duration
+	Buckets      []db.StatsBucket
+	TotalPackets int64
+	Totals       db.StatsBucket
+	SoldOut      SoldOut
+}
+
+// Service serves campaign analytics from the rollup tables maintained by the consumer.
+type Service struct {
+	store *db.Store
+}
+
+// NewService wires dependencies.
+func NewService(store *db.Store) *Service {
+	return &Service{store: store}
+}
+
+// ValidateStep checks a bucket width is a whole number of minutes up to a day.
+func ValidateStep(step time.Duration) error {
+	if step < time.Minute || step > 24*time.Hour || step%time.Minute != 0 {
+		return fmt.Errorf("%w: bucket must be a whole number of minutes between 1m and 24h", ErrInvalidQuery)
+	}
+	return nil
+}
+
+// Stats builds the report for a campaign.
+func (s *Service) Stats(ctx context.Context, campaignID int64, q Query) (*Report, error) {
+	if err := ValidateStep(q.Step); err != nil {
+		return nil, err
+	}
+	campaign, err := s.store.GetCampaign(ctx, campaignID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCampaignNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	inventory, err := s.store.ListCampaignInventory(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	from, to := q.From, q.To
+	if from.IsZero() {
+		from = campaign.StartTime
+	}
+	if to.IsZero() {
+		to = campaign.EndTime
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidQuery)
+	}
+
+	buckets, err := s.store.ListCampaignStats(ctx, campaignID, q.Step, campaign.StartTime, from, to)
+	if err != nil {
+		return nil, err
+	}
+	// Sell-out timings need every minute from the start, regardless of the requested range.
+	minutes, err := s.store.ListCampaignStats(ctx, campaignID, time.Minute, campaign.StartTime, time.Time{}, campaign.EndTime.Add(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Campaign: *campaign, Inventory: inventory, Step: q.Step, Buckets: buckets}
+	report.Totals.TierClaims = make(map[int64]int64)
+	for _, inv := range inventory {
+		report.TotalPackets += int64(inv.InitialTotal)
+	}
+	for _, b := range buckets {
+		report.Totals.Claims += b.Claims
+		report.Totals.UniqueUsers += b.UniqueUsers
+		report.Totals.AmountSum += b.AmountSum
+		for amount, claims := range b.TierClaims {
+			report.Totals.TierClaims[amount] += claims
+		}
+	}
+	report.SoldOut = soldOut(campaign.StartTime, inventory, report.TotalPackets, minutes)
+	return report, nil
+}
+
+// soldOut walks the minute rollups and records when cumulative claims crossed
+// 50%, 95% and 100% of all packets, and when each tier ran out. Times are
+// measured to the end of the minute in which the threshold was crossed.
+func soldOut(start time.Time, inventory []db.CampaignInventory, total int64, minutes []db.StatsBucket) SoldOut {
+	result := SoldOut{PerTier: make(map[int64]*time.Duration, len(inventory))}
+	if total == 0 {
+		return result
+	}
+	thresholds := []struct {
+		share float64
+		dst   **time.Duration
+	}{{0.5, &result.P50}, {0.95, &result.P95}, {1, &result.P100}}
+
+	tierTotals := make(map[int64]int64, len(inventory))
+	for _, inv := range inventory {
+		tierTotals[inv.Amount] = int64(inv.InitialTotal)
+		result.PerTier[inv.Amount] = nil
+	}
+	var claimed int64
+	tierClaimed := make(map[int64]int64, len(inventory))
+	for _, m := range minutes {
+		elapsed := m.Start.Add(time.Minute).Sub(start)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		claimed += m.Claims
+		for _, t := range thresholds {
+			if *t.dst == nil && claimed >= int64(math.Ceil(t.share*float64(total))) {
+				d := elapsed
+				*t.dst = &d
+			}
+		}
+		for amount, claims := range m.TierClaims {
+			tierClaimed[amount] += claims
+			if tierTotal, ok := tierTotals[amount]; ok && result.PerTier[amount] == nil && tierClaimed[amount] >= tierTotal {
+				d := elapsed
+				result.PerTier[amount] = &d
+			}
+		}
+	}
+	return result
+}
@@ -2,38 +2,106 @@ package campaign
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+
 	"redpacket/internal/db"
+	"redpacket/internal/domain/ledger"
+	"redpacket/internal/domain/payout"
+	"redpacket/internal/observability/logging"
 	"redpacket/internal/observability/metrics"
 )
 
 // ClaimRecorder persists claim events using the store.
 type ClaimRecorder struct {
-	store *db.Store
+	store  *db.Store
+	ledger *ledger.Poster
+	logger *slog.Logger
 }
 
 // NewClaimRecorder builds a recorder.
-func NewClaimRecorder(store *db.Store) *ClaimRecorder {
-	return &ClaimRecorder{store: store}
+func NewClaimRecorder(store *db.Store, logger *slog.Logger) *ClaimRecorder {
+	return &ClaimRecorder{store: store, ledger: ledger.NewPoster(store), logger: logger.With(slog.String("component", "claim_recorder"))}
 }
 
-// HandleClaim processes a claim event by inserting logs and updating counters.
+// HandleClaim processes a claim event by inserting logs, updating counters,
+// posting the matching ledger entries, crediting the user's wallet, rolling up
+// campaign stats and enqueuing a payout in a single transaction. A user claims
+// at most once per campaign, so an event for a claim that is already recorded
+// is a redelivery and is dropped. Log lines take the event's IDs from ctx; see
+// claim.NewConsumer.
 func (r *ClaimRecorder) HandleClaim(ctx context.Context, event ClaimEvent) error {
 	start := time.Now()
-	defer metrics.ObserveConsumerProcessing("handle_claim", time.Since(start))
-	if err := r.store.InsertClaimLog(ctx, db.ClaimLog{
-		UserID:     event.UserID,
-		CampaignID: event.CampaignID,
-		Amount:     event.Amount,
-	}); err != nil {
-		log.Printf("claim recorder: failed to insert log for campaign=%d user=%s: %v", event.CampaignID, event.UserID, err)
-		return err
+	defer func() { metrics.ObserveConsumerProcessing("handle_claim", time.Since(start)) }()
+	// Claims are dated when they were made, not when they are consumed.
+	// Events from before ClaimEvent.Timestamp existed fall back to now.
+	claimedAt := event.Timestamp
+	if claimedAt.IsZero() {
+		claimedAt = time.Now()
 	}
-	if err := r.store.IncrementOpenedCount(ctx, event.CampaignID, event.Amount); err != nil {
-		log.Printf("claim recorder: failed to increment opened count for campaign=%d amount=%d: %v", event.CampaignID, event.Amount, err)
+	var claimLogID int64
+	duplicate := false
+	err := r.store.RunInTx(ctx, func(tx pgx.Tx) error {
+		first, err := r.store.InsertClaimDedupeTx(ctx, tx, event.CampaignID, event.UserID)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "failed to record claim dedupe key", logging.Err(err))
+			return err
+		}
+		if !first {
+			duplicate = true
+			return nil
+		}
+		claimLogID, err = r.store.InsertClaimLogTx(ctx, tx, db.ClaimLog{
+			UserID:     event.UserID,
+			CampaignID: event.CampaignID,
+			Amount:     event.Amount,
+			Currency:   event.Currency,
+			CreatedAt:  claimedAt,
+		})
+		if err != nil {
+			r.logger.ErrorContext(ctx, "failed to insert claim log", logging.Err(err))
+			return err
+		}
+		if err := r.store.IncrementOpenedCountTx(ctx, tx, event.CampaignID, event.Amount, event.Currency); err != nil {
+			r.logger.ErrorContext(ctx, "failed to increment opened count", slog.Int64("amount", event.Amount), slog.String("currency", event.Currency), logging.Err(err))
+			return err
+		}
+		if err := r.ledger.PostClaimTx(ctx, tx, event.CampaignID, claimLogID, event.UserID, event.Amount, event.Currency); err != nil {
+			r.logger.ErrorContext(ctx, "failed to post ledger entries", logging.Err(err))
+			return err
+		}
+		// The wallet credit is not idempotent by itself; the dedupe key
+		// above keeps a redelivery from reaching it.
+		if err := r.store.CreditUserWalletTx(ctx, tx, event.UserID, event.Currency, event.Amount); err != nil {
+			r.logger.ErrorContext(ctx, "failed to credit wallet", logging.Err(err))
+			return err
+		}
+		if err := r.store.RecordClaimStatsTx(ctx, tx, event.CampaignID, claimLogID, event.UserID, event.Amount, claimedAt); err != nil {
+			r.logger.ErrorContext(ctx, "failed to record claim stats", logging.Err(err))
+			return err
+		}
+		if err := r.store.InsertPayoutTx(ctx, tx, db.Payout{
+			ClaimLogID:     claimLogID,
+			CampaignID:     event.CampaignID,
+			UserID:         event.UserID,
+			Amount:         event.Amount,
+			Currency:       event.Currency,
+			IdempotencyKey: payout.IdempotencyKey(event.CampaignID, event.UserID),
+		}); err != nil {
+			r.logger.ErrorContext(ctx, "failed to enqueue payout", logging.Err(err))
+			return err
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	if duplicate {
+		r.logger.WarnContext(ctx, "duplicate claim event dropped")
+		return nil
+	}
+	r.logger.LogAttrs(ctx, slog.LevelInfo, "claim recorded", slog.Int64("claim_log_id", claimLogID), slog.Int64("amount", event.Amount), slog.String("currency", event.Currency))
 	return nil
 }
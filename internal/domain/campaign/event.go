@@ -3,9 +3,13 @@ package campaign
 import "time"
 
 // ClaimEvent encapsulates the data emitted after a user claim succeeds.
+// Amount is expressed in minor units of Currency. ClaimID is generated when
+// the packet is opened and ties the open's log lines to the consumer's.
 type ClaimEvent struct {
+	ClaimID    string    `json:"claim_id,omitempty"`
 	UserID     string    `json:"user_id"`
 	CampaignID int64     `json:"campaign_id"`
-	Amount     int       `json:"amount"`
+	Amount     int64     `json:"amount"`
+	Currency   string    `json:"currency"`
 	Timestamp  time.Time `json:"ts"`
 }
@@ -0,0 +1,77 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"redpacket/internal/domain/waitroom"
+	"redpacket/internal/observability/logging"
+	"redpacket/internal/observability/metrics"
+)
+
+// InventoryReporterConfig tunes the InventoryReporter loop.
+type InventoryReporterConfig struct {
+	// Interval between refreshes.
+	Interval time.Duration
+	// MaxCampaigns caps the campaigns labelled by their own id in metrics.
+	MaxCampaigns int
+}
+
+// InventoryReporter picks the live campaigns that get their own campaign_id
+// label in the campaign metrics, and refreshes their remaining packets and
+// waiting room depth from Redis.
+type InventoryReporter struct {
+	svc    *Service
+	rooms  *waitroom.Service
+	cfg    InventoryReporterConfig
+	logger *slog.Logger
+}
+
+// NewInventoryReporter builds an InventoryReporter.
+func NewInventoryReporter(svc *Service, rooms *waitroom.Service, cfg InventoryReporterConfig, logger *slog.Logger) *InventoryReporter {
+	return &InventoryReporter{svc: svc, rooms: rooms, cfg: cfg, logger: logger.With(slog.String("component", "inventory_reporter"))}
+}
+
+// Run refreshes the metrics periodically until ctx is canceled.
+func (r *InventoryReporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := r.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "inventory refresh failed", logging.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce tracks the live campaigns and records their remaining packets and
+// waiting room depth.
+func (r *InventoryReporter) RunOnce(ctx context.Context) error {
+	ids, err := r.svc.store.ListLiveCampaigns(ctx, r.cfg.MaxCampaigns)
+	if err != nil {
+		return err
+	}
+	metrics.SetTrackedCampaigns(ids)
+	for _, id := range ids {
+		remaining, err := r.svc.redis.RemainingPackets(ctx, id)
+		if err != nil {
+			r.logger.WarnContext(ctx, "failed to read remaining packets", logging.CampaignID(id), logging.Err(err))
+		} else {
+			metrics.SetCampaignPacketsRemaining(id, remaining)
+		}
+		depth, rate, err := r.rooms.Depth(ctx, id)
+		switch {
+		case err == nil:
+			metrics.SetWaitingRoom(id, depth, rate)
+		case !errors.Is(err, waitroom.ErrNoWaitingRoom):
+			r.logger.WarnContext(ctx, "failed to read waiting room depth", logging.CampaignID(id), logging.Err(err))
+		}
+	}
+	return nil
+}
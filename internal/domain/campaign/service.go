@@ -2,11 +2,16 @@ package campaign
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"redpacket/internal/db"
+	"redpacket/internal/domain/ledger"
+	"redpacket/internal/domain/money"
+	"redpacket/internal/observability/logging"
 	redisClient "redpacket/internal/redis"
 
 	"github.com/jackc/pgx/v5"
@@ -18,6 +23,7 @@ const (
 	StatusSoldOut          = "SOLD_OUT"
 	StatusCampaignInactive = "CAMPAIGN_INACTIVE"
 	StatusCampaignNotFound = "CAMPAIGN_NOT_FOUND"
+	StatusClaimPending     = "PENDING"
 )
 
 // ErrCampaignNotFound indicates the campaign is missing.
@@ -26,38 +32,90 @@ var ErrCampaignNotFound = errors.New("campaign not found")
 // ErrCampaignInactive indicates the campaign is outside its schedule.
 var ErrCampaignInactive = errors.New("campaign not active")
 
+// ErrClaimNotFound indicates the user has not opened the campaign.
+var ErrClaimNotFound = errors.New("claim not found")
+
 // Service coordinates DB + Redis operations.
 type Service struct {
-	store *db.Store
-	redis *redisClient.Client
+	store    *db.Store
+	redis    *redisClient.Client
+	ledger   *ledger.Poster
+	warmLead time.Duration
+	cache    *StateCache
+	logger   *slog.Logger
 }
 
-// CreateInput captures campaign creation payload.
+// CreateInput captures campaign creation payload. Inventory maps amounts in
+// minor units of Currency to packet counts. Rules is an optional opaque JSON
+// object stored with the campaign. Shards splits the inventory across that
+// many Redis slots for hot campaigns; zero means one. ClaimMode is
+// redis.ClaimModeCounter (the default) or redis.ClaimModeQueue. A non-nil
+// AdmissionRate puts the campaign behind a waiting room admitting that many
+// users per second. ScheduleID and Occurrence are set when a schedule creates
+// the campaign.
 type CreateInput struct {
-	Name      string
-	Inventory map[int]int
-	StartTime time.Time
-	EndTime   time.Time
+	Name          string
+	Currency      string
+	Inventory     map[int64]int
+	Rules         []byte
+	TemplateID    *int64
+	ScheduleID    *int64
+	Occurrence    *time.Time
+	Shards        int
+	ClaimMode     string
+	AdmissionRate *int
+	StartTime     time.Time
+	EndTime       time.Time
 }
 
 // OpenResult represents the outcome of opening a red packet.
 type OpenResult struct {
-	Status string
-	Amount int
+	Status   string
+	Amount   int64
+	Currency string
+}
+
+// UserClaim is a user's outcome for a campaign. Status is StatusClaimPending
+// while the claim is accepted in Redis but not yet persisted by the consumer.
+type UserClaim struct {
+	Status    string
+	Amount    int64
+	Currency  string
+	ClaimedAt time.Time
 }
 
-// NewService wires dependencies.
-func NewService(store *db.Store, redis *redisClient.Client) *Service {
-	return &Service{store: store, redis: redis}
+// NewService wires dependencies. Campaigns are primed in Redis warmLead before
+// they start; see Warmer. A nil cache runs the claim script for every open.
+func NewService(store *db.Store, redis *redisClient.Client, warmLead time.Duration, cache *StateCache, logger *slog.Logger) *Service {
+	return &Service{store: store, redis: redis, ledger: ledger.NewPoster(store), warmLead: warmLead, cache: cache, logger: logger.With(slog.String("component", "campaign_service"))}
 }
 
-// CreateCampaign persists metadata and primes Redis inventory.
+// CreateCampaign persists a scheduled campaign in Postgres. Redis is primed
+// right away only if the campaign starts within the warm lead; otherwise the
+// Warmer primes it later.
 func (s *Service) CreateCampaign(ctx context.Context, in CreateInput) (int64, error) {
 	if in.Name == "" {
 		return 0, errors.New("name is required")
 	}
-	if len(in.Inventory) == 0 {
-		return 0, errors.New("inventory is required")
+	if err := money.ValidateCurrency(in.Currency); err != nil {
+		return 0, err
+	}
+	if err := validateInventory(in.Inventory); err != nil {
+		return 0, err
+	}
+	if err := validateRules(in.Rules); err != nil {
+		return 0, err
+	}
+	if in.Shards < 0 || in.Shards > redisClient.MaxShards {
+		return 0, fmt.Errorf("shards must be between 1 and %d", redisClient.MaxShards)
+	}
+	switch in.ClaimMode {
+	case "", redisClient.ClaimModeCounter, redisClient.ClaimModeQueue:
+	default:
+		return 0, fmt.Errorf("claim mode must be %q or %q", redisClient.ClaimModeCounter, redisClient.ClaimModeQueue)
+	}
+	if in.AdmissionRate != nil && *in.AdmissionRate <= 0 {
+		return 0, errors.New("admission rate must be positive")
 	}
 	if in.StartTime.IsZero() || in.EndTime.IsZero() {
 		return 0, errors.New("start and end time required")
@@ -67,23 +125,34 @@ func (s *Service) CreateCampaign(ctx context.Context, in CreateInput) (int64, er
 	}
 
 	entries := make([]db.CampaignInventoryInput, 0, len(in.Inventory))
+	var fundedTotal int64
 	for amount, count := range in.Inventory {
-		if amount <= 0 {
-			return 0, fmt.Errorf("invalid amount %d", amount)
-		}
-		if count <= 0 {
-			return 0, fmt.Errorf("invalid count for amount %d", amount)
-		}
 		entries = append(entries, db.CampaignInventoryInput{Amount: amount, Count: count})
+		fundedTotal += amount * int64(count)
 	}
 
 	var campaignID int64
 	if err := s.store.RunInTx(ctx, func(tx pgx.Tx) error {
-		id, err := s.store.InsertCampaignTx(ctx, tx, in.Name, in.StartTime, in.EndTime)
+		id, err := s.store.InsertCampaignTx(ctx, tx, db.CampaignInput{
+			Name:          in.Name,
+			Currency:      in.Currency,
+			Rules:         in.Rules,
+			TemplateID:    in.TemplateID,
+			ScheduleID:    in.ScheduleID,
+			Occurrence:    in.Occurrence,
+			Shards:        in.Shards,
+			ClaimMode:     in.ClaimMode,
+			AdmissionRate: in.AdmissionRate,
+			StartTime:     in.StartTime,
+			EndTime:       in.EndTime,
+		})
 		if err != nil {
 			return err
 		}
-		if err := s.store.InsertCampaignInventoryTx(ctx, tx, id, entries); err != nil {
+		if err := s.store.InsertCampaignInventoryTx(ctx, tx, id, in.Currency, entries); err != nil {
+			return err
+		}
+		if err := s.ledger.FundCampaignTx(ctx, tx, id, fundedTotal, in.Currency); err != nil {
 			return err
 		}
 		campaignID = id
@@ -91,55 +160,117 @@ func (s *Service) CreateCampaign(ctx context.Context, in CreateInput) (int64, er
 	}); err != nil {
 		return 0, err
 	}
-	if err := s.redis.InitializeInventory(ctx, campaignID, in.Inventory); err != nil {
-		return 0, err
-	}
-	if err := s.redis.SetCampaignWindow(ctx, campaignID, in.StartTime, in.EndTime); err != nil {
-		return 0, err
+	if !in.StartTime.After(time.Now().Add(s.warmLead)) {
+		if _, err := s.WarmCampaign(ctx, campaignID); err != nil {
+			s.logger.WarnContext(ctx, "failed to warm campaign, the warmer will retry", logging.CampaignID(campaignID), logging.Err(err))
+		}
 	}
 	return campaignID, nil
 }
 
-// OpenRedPacket runs the Lua script to atomically assign an amount.
-func (s *Service) OpenRedPacket(ctx context.Context, campaignID int64, userID string) (*OpenResult, error) {
+// OpenRedPacket runs the Lua script to atomically assign an amount. With a
+// state cache, opens for campaigns known to be sold out, inactive or missing
+// are answered without Redis. A non-nil reserve is called just before the
+// script runs, so callers can take resources the claim needs, such as a Kafka
+// slot, only for opens that can hand out a packet; its error is returned
+// as is.
+func (s *Service) OpenRedPacket(ctx context.Context, campaignID int64, userID string, reserve func(context.Context) error) (*OpenResult, error) {
 	if userID == "" {
 		return nil, errors.New("user id required")
 	}
-	keys := []string{
-		s.redis.OpenedKey(campaignID),
-		s.redis.CampaignWindowKey(campaignID),
-		s.redis.AmountsKey(campaignID),
+	now := time.Now()
+	if s.cache != nil {
+		if result, err := s.cache.check(ctx, campaignID, now); result != nil || err != nil {
+			return result, err
+		}
+	}
+	if reserve != nil {
+		if err := reserve(ctx); err != nil {
+			return nil, err
+		}
 	}
-	args := []interface{}{userID, time.Now().Unix(), fmt.Sprintf("%d", campaignID)}
-
-	resp, err := s.redis.RunClaimScript(ctx, keys, args...)
+	resp, err := s.redis.RunClaimScript(ctx, campaignID, userID, now)
 	if err != nil {
 		return nil, err
 	}
 
 	status := fmt.Sprintf("%v", resp[0])
 	amount := parseAmount(resp[1])
+	currency, _ := resp[2].(string)
+	s.logger.LogAttrs(ctx, slog.LevelDebug, "claim script answered", slog.String("status", status), slog.Int64("amount", amount))
 
 	switch status {
 	case StatusCampaignNotFound:
 		return nil, ErrCampaignNotFound
 	case StatusCampaignInactive:
 		return nil, ErrCampaignInactive
+	case StatusSoldOut:
+		if s.cache != nil {
+			s.cache.markSoldOut(ctx, campaignID)
+		}
+	}
+
+	return &OpenResult{Status: status, Amount: amount, Currency: currency}, nil
+}
+
+// GetUserClaim restores a user's outcome for a campaign, e.g. after a reconnect.
+func (s *Service) GetUserClaim(ctx context.Context, campaignID int64, userID string) (*UserClaim, error) {
+	if userID == "" {
+		return nil, errors.New("user id required")
+	}
+	logEntry, err := s.store.GetClaimLog(ctx, campaignID, userID)
+	if err == nil {
+		return &UserClaim{Status: StatusOK, Amount: logEntry.Amount, Currency: logEntry.Currency, ClaimedAt: logEntry.CreatedAt}, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	opened, err := s.redis.HasOpened(ctx, campaignID, userID)
+	if err != nil {
+		return nil, err
 	}
+	if !opened {
+		return nil, ErrClaimNotFound
+	}
+	return &UserClaim{Status: StatusClaimPending}, nil
+}
 
-	return &OpenResult{Status: status, Amount: amount}, nil
+func validateInventory(inventory map[int64]int) error {
+	if len(inventory) == 0 {
+		return errors.New("inventory is required")
+	}
+	for amount, count := range inventory {
+		if amount <= 0 {
+			return fmt.Errorf("invalid amount %d", amount)
+		}
+		if count <= 0 {
+			return fmt.Errorf("invalid count for amount %d", amount)
+		}
+	}
+	return nil
+}
+
+func validateRules(rules []byte) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(rules, &obj); err != nil || obj == nil {
+		return errors.New("rules must be a JSON object")
+	}
+	return nil
 }
 
-func parseAmount(value interface{}) int {
+func parseAmount(value interface{}) int64 {
 	switch v := value.(type) {
 	case int64:
-		return int(v)
+		return v
 	case int32:
-		return int(v)
+		return int64(v)
 	case float64:
-		return int(v)
+		return int64(v)
 	case string:
-		var amt int
+		var amt int64
 		fmt.Sscanf(v, "%d", &amt)
 		return amt
 	default:
@@ -0,0 +1,151 @@
+package campaign
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"redpacket/internal/observability/logging"
+	"redpacket/internal/observability/metrics"
+	redisClient "redpacket/internal/redis"
+)
+
+// maxCachedCampaigns bounds the state cache, which is keyed by whatever
+// campaign ids clients send. The cache is cleared when it fills up.
+const maxCachedCampaigns = 10000
+
+// StateCache keeps each campaign's window, frozen flag and sold-out flag in
+// process, so opens that cannot succeed are answered without running the
+// claim script. Entries are read from Redis on first use and again after
+// TTL, and are updated or dropped by events on redis.CampaignStateChannel.
+type StateCache struct {
+	redis  *redisClient.Client
+	ttl    time.Duration
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	entries map[int64]*campaignState
+}
+
+type campaignState struct {
+	// window is nil while the campaign is not primed.
+	window    *redisClient.CampaignWindow
+	soldOut   bool
+	expiresAt time.Time
+}
+
+// NewStateCache builds a cache whose entries are reloaded after ttl.
+func NewStateCache(redis *redisClient.Client, ttl time.Duration, logger *slog.Logger) *StateCache {
+	return &StateCache{redis: redis, ttl: ttl, logger: logger.With(slog.String("component", "campaign_state_cache")), entries: make(map[int64]*campaignState)}
+}
+
+// Run applies campaign state events until ctx is canceled.
+func (c *StateCache) Run(ctx context.Context) error {
+	return c.redis.SubscribeCampaignState(ctx, c.clear, c.apply)
+}
+
+// check answers an open that is bound to fail from cached state. A nil result
+// and error mean the claim script has to run.
+func (c *StateCache) check(ctx context.Context, campaignID int64, now time.Time) (*OpenResult, error) {
+	state := c.load(ctx, campaignID, now)
+	if state == nil {
+		metrics.IncClaimGateDecision("script")
+		return nil, nil
+	}
+	w := state.window
+	switch {
+	case w == nil:
+		metrics.IncClaimGateDecision("not_found")
+		return nil, ErrCampaignNotFound
+	case w.Frozen || now.Unix() < w.Start.Unix() || now.Unix() > w.End.Unix():
+		metrics.IncClaimGateDecision("inactive")
+		return nil, ErrCampaignInactive
+	case state.soldOut:
+		metrics.IncClaimGateDecision("sold_out")
+		return &OpenResult{Status: StatusSoldOut, Currency: w.Currency}, nil
+	}
+	metrics.IncClaimGateDecision("script")
+	return nil, nil
+}
+
+// load returns the cached state, reading the window from Redis if the entry
+// is missing or stale. It returns nil if Redis could not be read.
+func (c *StateCache) load(ctx context.Context, campaignID int64, now time.Time) *campaignState {
+	c.mu.Lock()
+	state, ok := c.entries[campaignID]
+	c.mu.Unlock()
+	if ok && now.Before(state.expiresAt) {
+		return state
+	}
+	window, err := c.redis.GetCampaignWindow(ctx, campaignID)
+	if err != nil {
+		c.logger.WarnContext(ctx, "failed to load campaign state", logging.Err(err))
+		return nil
+	}
+	// A reload drops the sold-out flag: released claims and re-priming put
+	// packets back, and the next SOLD_OUT answer sets it again.
+	fresh := &campaignState{window: window, expiresAt: now.Add(c.ttl)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCachedCampaigns {
+		c.entries = make(map[int64]*campaignState)
+	}
+	c.entries[campaignID] = fresh
+	return fresh
+}
+
+// markSoldOut records a SOLD_OUT answer from the claim script and tells the
+// other instances about it.
+func (c *StateCache) markSoldOut(ctx context.Context, campaignID int64) {
+	if !c.setSoldOut(campaignID) {
+		return
+	}
+	if err := c.redis.PublishCampaignState(ctx, campaignID, redisClient.StateEventSoldOut); err != nil {
+		c.logger.WarnContext(ctx, "failed to publish sold out", logging.Err(err))
+	}
+}
+
+// setSoldOut flags a cached campaign as sold out and reports whether the flag
+// was newly set.
+func (c *StateCache) setSoldOut(campaignID int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.entries[campaignID]
+	if !ok || state.window == nil || state.soldOut {
+		return false
+	}
+	next := *state
+	next.soldOut = true
+	c.entries[campaignID] = &next
+	return true
+}
+
+func (c *StateCache) apply(event redisClient.CampaignStateEvent) {
+	switch event.Event {
+	case redisClient.StateEventSoldOut:
+		c.setSoldOut(event.CampaignID)
+	case redisClient.StateEventFrozen:
+		c.mu.Lock()
+		if state, ok := c.entries[event.CampaignID]; ok && state.window != nil {
+			next := *state
+			window := *state.window
+			window.Frozen = true
+			next.window = &window
+			c.entries[event.CampaignID] = &next
+		}
+		c.mu.Unlock()
+	default:
+		// Primed and restocked campaigns may have packets again.
+		c.mu.Lock()
+		delete(c.entries, event.CampaignID)
+		c.mu.Unlock()
+	}
+}
+
+// clear drops every entry, e.g. after a reconnect that may have missed events.
+func (c *StateCache) clear() {
+	c.mu.Lock()
+	c.entries = make(map[int64]*campaignState)
+	c.mu.Unlock()
+}
@@ -0,0 +1,174 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/db"
+	"redpacket/internal/domain/money"
+)
+
+// ErrTemplateNotFound indicates the campaign template is missing.
+var ErrTemplateNotFound = errors.New("campaign template not found")
+
+// TemplateInput captures a campaign template. NamePattern may use the
+// placeholders {name} (the template name), {date} (start date, 2006-01-02),
+// {week} (ISO week of the start, 2006-W01) and {start} (RFC3339), all in the
+// start time's own location, which for schedules is their timezone.
+type TemplateInput struct {
+	Name        string
+	NamePattern string
+	Currency    string
+	Inventory   map[int64]int
+	Rules       []byte
+	Duration    time.Duration
+}
+
+// CloneInput overrides parts of a cloned campaign. Name defaults to the
+// source's name and EndTime to StartTime plus the source's duration.
+type CloneInput struct {
+	Name      string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// CreateTemplate validates and stores a campaign template.
+func (s *Service) CreateTemplate(ctx context.Context, in TemplateInput) (int64, error) {
+	if in.Name == "" {
+		return 0, errors.New("name is required")
+	}
+	if in.NamePattern == "" {
+		in.NamePattern = "{name} {date}"
+	}
+	if err := money.ValidateCurrency(in.Currency); err != nil {
+		return 0, err
+	}
+	if err := validateInventory(in.Inventory); err != nil {
+		return 0, err
+	}
+	if err := validateRules(in.Rules); err != nil {
+		return 0, err
+	}
+	if in.Duration < time.Second {
+		return 0, errors.New("duration must be at least one second")
+	}
+	return s.store.InsertCampaignTemplate(ctx, db.CampaignTemplate{
+		Name:        in.Name,
+		NamePattern: in.NamePattern,
+		Currency:    in.Currency,
+		Inventory:   in.Inventory,
+		Rules:       in.Rules,
+		Duration:    in.Duration.Truncate(time.Second),
+	})
+}
+
+// GetTemplate returns a template by id.
+func (s *Service) GetTemplate(ctx context.Context, id int64) (*db.CampaignTemplate, error) {
+	t, err := s.store.GetCampaignTemplate(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTemplateNotFound
+	}
+	return t, err
+}
+
+// ListTemplates returns every template.
+func (s *Service) ListTemplates(ctx context.Context) ([]db.CampaignTemplate, error) {
+	return s.store.ListCampaignTemplates(ctx)
+}
+
+// CreateFromTemplate creates a campaign from a template, starting at start and
+// lasting the template's duration.
+func (s *Service) CreateFromTemplate(ctx context.Context, templateID int64, start time.Time) (int64, error) {
+	t, err := s.GetTemplate(ctx, templateID)
+	if err != nil {
+		return 0, err
+	}
+	if start.IsZero() {
+		return 0, errors.New("start time required")
+	}
+	return s.CreateCampaign(ctx, templateInput(t, start))
+}
+
+// CreateScheduledCampaign creates the campaign for one occurrence of a
+// schedule from its template. It returns db.ErrOccurrenceExists if that
+// occurrence was already created.
+func (s *Service) CreateScheduledCampaign(ctx context.Context, scheduleID, templateID int64, occurrence time.Time) (int64, error) {
+	t, err := s.GetTemplate(ctx, templateID)
+	if err != nil {
+		return 0, err
+	}
+	in := templateInput(t, occurrence)
+	in.ScheduleID = &scheduleID
+	in.Occurrence = &occurrence
+	return s.CreateCampaign(ctx, in)
+}
+
+func templateInput(t *db.CampaignTemplate, start time.Time) CreateInput {
+	return CreateInput{
+		Name:       RenderName(t.NamePattern, t.Name, start),
+		Currency:   t.Currency,
+		Inventory:  t.Inventory,
+		Rules:      t.Rules,
+		TemplateID: &t.ID,
+		StartTime:  start,
+		EndTime:    start.Add(t.Duration),
+	}
+}
+
+// CloneCampaign creates a new scheduled campaign with the source campaign's
+// currency, rules, shard count, claim mode, admission rate and initial
+// inventory.
+func (s *Service) CloneCampaign(ctx context.Context, sourceID int64, in CloneInput) (int64, error) {
+	src, err := s.store.GetCampaign(ctx, sourceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrCampaignNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if in.StartTime.IsZero() {
+		return 0, errors.New("start time required")
+	}
+	tiers, err := s.store.ListCampaignInventory(ctx, sourceID)
+	if err != nil {
+		return 0, err
+	}
+	inventory := make(map[int64]int, len(tiers))
+	for _, t := range tiers {
+		inventory[t.Amount] = t.InitialTotal
+	}
+	if in.Name == "" {
+		in.Name = src.Name
+	}
+	if in.EndTime.IsZero() {
+		in.EndTime = in.StartTime.Add(src.EndTime.Sub(src.StartTime))
+	}
+	return s.CreateCampaign(ctx, CreateInput{
+		Name:          in.Name,
+		Currency:      src.Currency,
+		Inventory:     inventory,
+		Rules:         src.Rules,
+		TemplateID:    src.TemplateID,
+		Shards:        src.Shards,
+		ClaimMode:     src.ClaimMode,
+		AdmissionRate: src.AdmissionRate,
+		StartTime:     in.StartTime,
+		EndTime:       in.EndTime,
+	})
+}
+
+// RenderName expands a template name pattern for a campaign starting at start.
+func RenderName(pattern, templateName string, start time.Time) string {
+	year, week := start.ISOWeek()
+	return strings.NewReplacer(
+		"{name}", templateName,
+		"{date}", start.Format("2006-01-02"),
+		"{week}", fmt.Sprintf("%d-W%02d", year, week),
+		"{start}", start.Format(time.RFC3339),
+	).Replace(pattern)
+}
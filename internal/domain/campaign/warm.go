@@ -0,0 +1,306 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/db"
+	"redpacket/internal/observability/logging"
+	"redpacket/internal/observability/metrics"
+	redisClient "redpacket/internal/redis"
+)
+
+// Readiness reports whether a campaign's Redis state is in place. Ready
+// requires both a recorded warm-up and the window key still being present.
+type Readiness struct {
+	Ready       bool
+	WarmedAt    *time.Time
+	WarmDueAt   time.Time
+	RedisPrimed bool
+}
+
+// Details is a campaign with its inventory and readiness.
+type Details struct {
+	Campaign  db.Campaign
+	Inventory []db.CampaignInventory
+	Readiness Readiness
+}
+
+// WarmerConfig tunes the Warmer loop.
+type WarmerConfig struct {
+	// Interval between passes.
+	Interval time.Duration
+	// BatchSize caps the campaigns warmed per pass.
+	BatchSize int
+}
+
+// ClaimBacklog reports whether every claim event sent up to a time has been
+// recorded by the claim consumer.
+type ClaimBacklog interface {
+	DrainedThrough(ctx context.Context, t time.Time) (bool, error)
+}
+
+// reprimeDrainMargin covers claim events that were still being sent when a
+// campaign's keys went missing; sends are bounded by the Kafka call timeout.
+const reprimeDrainMargin = 10 * time.Second
+
+// Warmer primes Redis for campaigns that start within the service's warm
+// lead, and primes warmed campaigns again if their window key went missing.
+// Replicas may run it concurrently: each campaign is primed under a Redis
+// lease.
+type Warmer struct {
+	svc    *Service
+	claims ClaimBacklog
+	cfg    WarmerConfig
+	logger *slog.Logger
+
+	// missingSince records when each live campaign was first seen without
+	// its window key. It is only used by the Run goroutine.
+	missingSince map[int64]time.Time
+}
+
+// NewWarmer builds a Warmer.
+func NewWarmer(svc *Service, claims ClaimBacklog, cfg WarmerConfig, logger *slog.Logger) *Warmer {
+	return &Warmer{svc: svc, claims: claims, cfg: cfg, logger: logger.With(slog.String("component", "campaign_warmer")), missingSince: make(map[int64]time.Time)}
+}
+
+// Run warms due campaigns periodically until ctx is canceled.
+func (w *Warmer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.logger.ErrorContext(ctx, "warm pass failed", logging.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce warms every due campaign, primes warmed ones whose Redis state is
+// gone and refreshes the started-unwarmed gauge.
+func (w *Warmer) RunOnce(ctx context.Context) error {
+	due, err := w.svc.store.ListCampaignsDueForWarming(ctx, w.svc.warmLead, w.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, id := range due {
+		if _, err := w.svc.WarmCampaign(ctx, id); err != nil {
+			w.logger.ErrorContext(ctx, "failed to warm campaign", logging.CampaignID(id), logging.Err(err))
+		}
+	}
+	if err := w.reprime(ctx); err != nil {
+		return err
+	}
+	unwarmed, err := w.svc.store.CountStartedUnwarmedCampaigns(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.SetCampaignsStartedUnwarmed(unwarmed)
+	return nil
+}
+
+// reprime primes warmed, unsettled campaigns whose window key is missing,
+// for example after a Redis flush or failover. Inventory and opened sets are
+// rebuilt from Postgres, so a campaign stays unprimed, and unclaimable,
+// until the claim consumer has recorded every claim sent before its keys
+// were found missing.
+func (w *Warmer) reprime(ctx context.Context) error {
+	var after int64
+	seen := make(map[int64]bool)
+	for {
+		ids, err := w.svc.store.ListWarmedLiveCampaigns(ctx, after, w.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			seen[id] = true
+			primed, err := w.svc.redis.CampaignPrimed(ctx, id)
+			if err != nil {
+				return err
+			}
+			if primed {
+				delete(w.missingSince, id)
+				continue
+			}
+			c, err := w.svc.store.GetCampaign(ctx, id)
+			if err != nil {
+				return err
+			}
+			// The campaign may have ended since it was listed.
+			if (c.Status != db.CampaignScheduled && c.Status != db.CampaignActive) || !c.EndTime.After(time.Now()) {
+				continue
+			}
+			since, ok := w.missingSince[id]
+			if !ok {
+				since = time.Now()
+				w.missingSince[id] = since
+				w.logger.WarnContext(ctx, "campaign Redis state is missing, waiting for pending claims before priming again", logging.CampaignID(id))
+			}
+			cutoff := since.Add(reprimeDrainMargin)
+			if time.Now().Before(cutoff) {
+				continue
+			}
+			drained, err := w.claims.DrainedThrough(ctx, cutoff)
+			if err != nil {
+				w.logger.ErrorContext(ctx, "failed to check the claim backlog", logging.CampaignID(id), logging.Err(err))
+				continue
+			}
+			if !drained {
+				continue
+			}
+			reprimed, err := w.svc.primeIfMissing(ctx, c)
+			if errors.Is(err, errPrimingElsewhere) {
+				continue
+			}
+			if err != nil {
+				w.logger.ErrorContext(ctx, "failed to prime campaign again", logging.CampaignID(id), logging.Err(err))
+				continue
+			}
+			delete(w.missingSince, id)
+			if reprimed {
+				metrics.IncCampaignsReprimed()
+				w.logger.WarnContext(ctx, "campaign primed again after its Redis state went missing", logging.CampaignID(id))
+			}
+		}
+		if len(ids) < w.cfg.BatchSize {
+			for id := range w.missingSince {
+				if !seen[id] {
+					delete(w.missingSince, id)
+				}
+			}
+			return nil
+		}
+		after = ids[len(ids)-1]
+	}
+}
+
+// warmLockTTL bounds how long a crashed replica blocks priming a campaign.
+const warmLockTTL = 5 * time.Minute
+
+// errPrimingElsewhere reports that another replica holds a campaign's warm
+// lease.
+var errPrimingElsewhere = errors.New("campaign is being primed elsewhere")
+
+// WarmCampaign primes the campaign's inventory, window and rules in Redis
+// and records warmed_at. It reports false if the campaign was already warmed
+// or is being primed elsewhere. No row lock is held while Redis is primed.
+func (s *Service) WarmCampaign(ctx context.Context, campaignID int64) (bool, error) {
+	c, err := s.store.GetCampaign(ctx, campaignID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if c.WarmedAt != nil {
+		return false, nil
+	}
+	if _, err := s.primeIfMissing(ctx, c); err != nil {
+		if errors.Is(err, errPrimingElsewhere) {
+			return false, nil
+		}
+		return false, err
+	}
+	warmed, err := s.store.MarkCampaignWarmed(ctx, campaignID)
+	if err != nil || !warmed {
+		return false, err
+	}
+	if time.Now().After(c.StartTime) {
+		metrics.IncCampaignsWarmedLate()
+		s.logger.WarnContext(ctx, "campaign warmed after its start", logging.CampaignID(campaignID), slog.Duration("late_by", time.Since(c.StartTime).Round(time.Second)))
+	}
+	return true, nil
+}
+
+// primeIfMissing primes the campaign unless its window key exists, holding a
+// Redis lease so that replicas never prime it at the same time. A window key
+// that exists may already be taking claims, so it is never primed over. It
+// reports whether it primed the campaign, and returns errPrimingElsewhere if
+// another replica holds the lease.
+func (s *Service) primeIfMissing(ctx context.Context, c *db.Campaign) (bool, error) {
+	owner := logging.NewID()
+	key := s.redis.WarmLockKey(c.ID)
+	held, err := s.redis.AcquireLock(ctx, key, owner, warmLockTTL)
+	if err != nil {
+		return false, err
+	}
+	if !held {
+		return false, errPrimingElsewhere
+	}
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := s.redis.ReleaseLock(releaseCtx, key, owner); err != nil {
+			s.logger.WarnContext(ctx, "failed to release warm lease", logging.CampaignID(c.ID), logging.Err(err))
+		}
+	}()
+	primed, err := s.redis.CampaignPrimed(ctx, c.ID)
+	if err != nil || primed {
+		return false, err
+	}
+	return true, s.primeRedis(ctx, c)
+}
+
+// primeRedis writes inventory before the window, so the claim script never
+// sees an open window without inventory. Users whose claims are recorded are
+// put back in the opened sets and leaderboards, so priming a campaign again
+// does not let them open twice.
+func (s *Service) primeRedis(ctx context.Context, c *db.Campaign) error {
+	tiers, err := s.store.ListCampaignInventory(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	inventory := make(map[int64]int, len(tiers))
+	for _, t := range tiers {
+		inventory[t.Amount] = t.InitialTotal - t.OpenedCount
+	}
+	winners, err := s.store.ListCampaignWinners(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.redis.InitializeInventory(ctx, c.ID, inventory, layoutOf(c), winners); err != nil {
+		return err
+	}
+	return s.redis.SetCampaignWindow(ctx, c.ID, c.StartTime, c.EndTime, c.Currency, string(c.Rules), layoutOf(c))
+}
+
+func layoutOf(c *db.Campaign) redisClient.Layout {
+	return redisClient.Layout{Shards: c.Shards, Mode: c.ClaimMode}
+}
+
+// GetCampaign returns a campaign with its inventory and Redis readiness.
+func (s *Service) GetCampaign(ctx context.Context, campaignID int64) (*Details, error) {
+	c, err := s.store.GetCampaign(ctx, campaignID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCampaignNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	inventory, err := s.store.ListCampaignInventory(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	primed, err := s.redis.CampaignPrimed(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	return &Details{
+		Campaign:  *c,
+		Inventory: inventory,
+		Readiness: Readiness{
+			Ready:       c.WarmedAt != nil && primed,
+			WarmedAt:    c.WarmedAt,
+			WarmDueAt:   c.StartTime.Add(-s.warmLead),
+			RedisPrimed: primed,
+		},
+	}, nil
+}
@@ -0,0 +1,145 @@
+package claimlog
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"redpacket/internal/db"
+	"redpacket/internal/observability/logging"
+)
+
+// Config tunes claim_log partition maintenance.
+type Config struct {
+	// Interval between maintenance passes.
+	Interval time.Duration
+	// PrecreateDays is how many future daily partitions to keep ready.
+	PrecreateDays int
+	// RetentionDays is how long a partition stays attached after its day ends. Zero disables archival.
+	RetentionDays int
+	// ArchiveDir receives one gzip-compressed CSV per archived partition.
+	ArchiveDir string
+}
+
+// Maintainer keeps claim_log partitions ahead of time and archives expired ones.
+type Maintainer struct {
+	store  *db.Store
+	cfg    Config
+	now    func() time.Time
+	logger *slog.Logger
+}
+
+// NewMaintainer builds a Maintainer.
+func NewMaintainer(store *db.Store, cfg Config, logger *slog.Logger) *Maintainer {
+	return &Maintainer{store: store, cfg: cfg, now: time.Now, logger: logger.With(slog.String("component", "claim_log_maintainer"))}
+}
+
+// Run maintains partitions periodically until ctx is canceled.
+func (m *Maintainer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := m.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			m.logger.ErrorContext(ctx, "maintenance pass failed", logging.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce creates missing future partitions and archives partitions past
+// retention. A partition that still holds claims of an unsettled campaign
+// stays attached, since settlement and re-priming read them.
+func (m *Maintainer) RunOnce(ctx context.Context) error {
+	parts, err := m.store.ListClaimLogPartitions(ctx)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(parts))
+	for _, p := range parts {
+		existing[p.Name] = true
+	}
+
+	today := m.now().UTC().Truncate(24 * time.Hour)
+	for i := 0; i <= m.cfg.PrecreateDays; i++ {
+		day := today.AddDate(0, 0, i)
+		if existing[db.ClaimLogPartitionName(day)] {
+			continue
+		}
+		if err := m.store.CreateClaimLogPartition(ctx, day); err != nil {
+			return fmt.Errorf("create partition for %s: %w", day.Format("2006-01-02"), err)
+		}
+		m.logger.InfoContext(ctx, "created partition", slog.String("partition", db.ClaimLogPartitionName(day)))
+	}
+
+	if m.cfg.RetentionDays <= 0 {
+		return nil
+	}
+	cutoff := today.AddDate(0, 0, -m.cfg.RetentionDays)
+	for _, p := range parts {
+		if p.End.After(cutoff) {
+			continue
+		}
+		if p.Attached {
+			held, err := m.store.ClaimLogPartitionHoldsUnsettled(ctx, p.Name)
+			if err != nil {
+				return fmt.Errorf("check partition %s: %w", p.Name, err)
+			}
+			if held {
+				m.logger.InfoContext(ctx, "keeping partition with claims of unsettled campaigns", slog.String("partition", p.Name))
+				continue
+			}
+		}
+		if err := m.archive(ctx, p); err != nil {
+			return fmt.Errorf("archive partition %s: %w", p.Name, err)
+		}
+		m.logger.InfoContext(ctx, "archived partition", slog.String("partition", p.Name))
+	}
+	return nil
+}
+
+// archive detaches, exports and drops a partition. Each step is safe to repeat
+// if a previous pass stopped half way.
+func (m *Maintainer) archive(ctx context.Context, p db.ClaimLogPartition) error {
+	if p.Attached {
+		if err := m.store.DetachClaimLogPartition(ctx, p.Name); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(m.cfg.ArchiveDir, 0o755); err != nil {
+		return err
+	}
+	final := filepath.Join(m.cfg.ArchiveDir, p.Name+".csv.gz")
+	tmp := final + ".tmp"
+	if err := m.export(ctx, p.Name, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		return err
+	}
+	return m.store.DropClaimLogPartition(ctx, p.Name)
+}
+
+func (m *Maintainer) export(ctx context.Context, name, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	if err := m.store.CopyClaimLogPartition(ctx, name, zw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
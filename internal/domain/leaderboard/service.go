@@ -0,0 +1,159 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/db"
+	redisClient "redpacket/internal/redis"
+)
+
+const (
+	// DefaultLimit is the board size when the caller does not ask for one.
+	DefaultLimit = 10
+	// MaxLimit caps the board size and is the size of the settlement snapshot.
+	MaxLimit = 100
+)
+
+// Board sources.
+const (
+	SourceLive     = "live"
+	SourceSnapshot = "snapshot"
+)
+
+// ErrCampaignNotFound indicates the campaign is missing.
+var ErrCampaignNotFound = errors.New("campaign not found")
+
+// Entry is one ranked winner. Rank is 1-based.
+type Entry struct {
+	Rank   int64
+	UserID string
+	Amount int64
+}
+
+// Query selects the board. UserID, when set, asks for that user's own rank.
+// Mask hides every user id on the board except the caller's.
+type Query struct {
+	Limit  int
+	UserID string
+	Mask   bool
+}
+
+// Board is a campaign's top winners. Me is nil if the caller has not claimed.
+type Board struct {
+	CampaignID int64
+	Currency   string
+	Source     string
+	Entries    []Entry
+	Me         *Entry
+}
+
+// Service reads the live board from Redis until the campaign settles and the
+// Postgres snapshot afterwards.
+type Service struct {
+	store *db.Store
+	redis *redisClient.Client
+}
+
+// NewService wires dependencies.
+func NewService(store *db.Store, redis *redisClient.Client) *Service {
+	return &Service{store: store, redis: redis}
+}
+
+// Top returns the campaign's leaderboard.
+func (s *Service) Top(ctx context.Context, campaignID int64, q Query) (*Board, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	campaign, err := s.store.GetCampaign(ctx, campaignID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCampaignNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	board := &Board{CampaignID: campaignID, Currency: campaign.Currency}
+	if campaign.Status == db.CampaignSettled {
+		err = s.fromSnapshot(ctx, board, q)
+	} else {
+		err = s.fromRedis(ctx, board, q)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if q.Mask {
+		for i := range board.Entries {
+			if board.Entries[i].UserID != q.UserID {
+				board.Entries[i].UserID = MaskUserID(board.Entries[i].UserID)
+			}
+		}
+	}
+	return board, nil
+}
+
+func (s *Service) fromRedis(ctx context.Context, board *Board, q Query) error {
+	board.Source = SourceLive
+	top, err := s.redis.TopWinners(ctx, board.CampaignID, q.Limit)
+	if err != nil {
+		return err
+	}
+	board.Entries = make([]Entry, 0, len(top))
+	for _, e := range top {
+		board.Entries = append(board.Entries, Entry(e))
+	}
+	if q.UserID == "" {
+		return nil
+	}
+	me, err := s.redis.WinnerRank(ctx, board.CampaignID, q.UserID)
+	if err != nil || me == nil {
+		return err
+	}
+	entry := Entry(*me)
+	board.Me = &entry
+	return nil
+}
+
+func (s *Service) fromSnapshot(ctx context.Context, board *Board, q Query) error {
+	board.Source = SourceSnapshot
+	rows, err := s.store.ListLeaderboardSnapshot(ctx, board.CampaignID, q.Limit)
+	if err != nil {
+		return err
+	}
+	board.Entries = make([]Entry, 0, len(rows))
+	for _, r := range rows {
+		board.Entries = append(board.Entries, Entry(r))
+	}
+	if q.UserID == "" {
+		return nil
+	}
+	me, err := s.store.GetClaimRank(ctx, board.CampaignID, q.UserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	entry := Entry(*me)
+	board.Me = &entry
+	return nil
+}
+
+// MaskUserID keeps the first and last two characters of a user id and stars
+// the rest. Ids of four characters or fewer keep only their first character.
+func MaskUserID(userID string) string {
+	runes := []rune(userID)
+	if len(runes) <= 4 {
+		if len(runes) == 0 {
+			return ""
+		}
+		return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+	}
+	return string(runes[:2]) + strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-2:])
+}
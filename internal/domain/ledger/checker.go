@@ -0,0 +1,62 @@
+package ledger
+
+import (
+	"context"
+
+	"redpacket/internal/db"
+)
+
+// Violation describes a campaign whose ledger does not reconcile.
+type Violation struct {
+	db.CampaignLedgerBalance
+	Reason string
+}
+
+// Report summarises an invariant check run.
+type Report struct {
+	CampaignsChecked int
+	Violations       []Violation
+	UnbalancedTxns   []int64
+}
+
+// OK reports whether every invariant held.
+func (r *Report) OK() bool {
+	return len(r.Violations) == 0 && len(r.UnbalancedTxns) == 0
+}
+
+// Checker verifies ledger invariants against campaign_inventory. It does not
+// read claim_log, whose old partitions are archived.
+type Checker struct {
+	store *db.Store
+}
+
+// NewChecker builds a Checker.
+func NewChecker(store *db.Store) *Checker {
+	return &Checker{store: store}
+}
+
+// Check proves every transaction is balanced, every campaign's escrow plus
+// claims equals its funded total, and its claim postings match its opened
+// packets.
+func (c *Checker) Check(ctx context.Context) (*Report, error) {
+	unbalanced, err := c.store.ListUnbalancedLedgerTxns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := c.store.ListCampaignLedgerBalances(ctx, KindEscrow, TxnFunding, TxnClaim)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{CampaignsChecked: len(balances), UnbalancedTxns: unbalanced}
+	for _, b := range balances {
+		switch {
+		case b.FundingPosted != b.FundedTotal:
+			report.Violations = append(report.Violations, Violation{CampaignLedgerBalance: b, Reason: "funding posted differs from inventory total"})
+		case b.EscrowBalance+b.ClaimedTotal != b.FundedTotal:
+			report.Violations = append(report.Violations, Violation{CampaignLedgerBalance: b, Reason: "escrow plus claims differs from funded total"})
+		case b.ClaimedTotal != b.OpenedTotal:
+			report.Violations = append(report.Violations, Violation{CampaignLedgerBalance: b, Reason: "claims posted differ from opened packets"})
+		}
+	}
+	return report, nil
+}
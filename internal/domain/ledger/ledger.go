@@ -0,0 +1,75 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/db"
+)
+
+// Account kinds.
+const (
+	KindFunding = "campaign_funding"
+	KindEscrow  = "campaign_escrow"
+	KindWallet  = "user_wallet"
+)
+
+// Transaction kinds.
+const (
+	TxnFunding = "funding"
+	TxnClaim   = "claim"
+)
+
+// FundingAccount is the merchant side that funds a campaign's escrow.
+func FundingAccount(campaignID int64, currency string) db.LedgerAccount {
+	return db.LedgerAccount{Code: fmt.Sprintf("campaign:%d:funding", campaignID), Kind: KindFunding, Currency: currency, CampaignID: &campaignID}
+}
+
+// EscrowAccount holds the unclaimed value of a campaign.
+func EscrowAccount(campaignID int64, currency string) db.LedgerAccount {
+	return db.LedgerAccount{Code: fmt.Sprintf("campaign:%d:escrow", campaignID), Kind: KindEscrow, Currency: currency, CampaignID: &campaignID}
+}
+
+// WalletAccount receives claimed money for a user in one currency.
+func WalletAccount(userID, currency string) db.LedgerAccount {
+	return db.LedgerAccount{Code: "user:" + userID + ":wallet:" + currency, Kind: KindWallet, Currency: currency, UserID: &userID}
+}
+
+// Poster writes balanced ledger transactions.
+type Poster struct {
+	store *db.Store
+}
+
+// NewPoster builds a Poster.
+func NewPoster(store *db.Store) *Poster {
+	return &Poster{store: store}
+}
+
+// FundCampaignTx moves the campaign's total value from merchant funding into escrow.
+func (p *Poster) FundCampaignTx(ctx context.Context, tx pgx.Tx, campaignID, total int64, currency string) error {
+	_, err := p.store.PostLedgerTransferTx(ctx, tx, db.LedgerTransfer{
+		Kind:       TxnFunding,
+		CampaignID: campaignID,
+		Debit:      FundingAccount(campaignID, currency),
+		Credit:     EscrowAccount(campaignID, currency),
+		Amount:     total,
+		Currency:   currency,
+	})
+	return err
+}
+
+// PostClaimTx debits the campaign escrow and credits the user's wallet.
+func (p *Poster) PostClaimTx(ctx context.Context, tx pgx.Tx, campaignID, claimLogID int64, userID string, amount int64, currency string) error {
+	_, err := p.store.PostLedgerTransferTx(ctx, tx, db.LedgerTransfer{
+		Kind:       TxnClaim,
+		CampaignID: campaignID,
+		ClaimLogID: &claimLogID,
+		Debit:      EscrowAccount(campaignID, currency),
+		Credit:     WalletAccount(userID, currency),
+		Amount:     amount,
+		Currency:   currency,
+	})
+	return err
+}
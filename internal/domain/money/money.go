@@ -0,0 +1,42 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+)
+
+// currencies lists the supported ISO 4217 currency codes. Amounts are always
+// integers in the currency's minor unit, so no exponent is needed to store or
+// validate them.
+var currencies = map[string]bool{
+	"CNY": true,
+	"HKD": true,
+	"TWD": true,
+	"USD": true,
+	"EUR": true,
+	"GBP": true,
+	"SGD": true,
+	"MYR": true,
+	"THB": true,
+	"PHP": true,
+	"IDR": true,
+	"JPY": true,
+	"KRW": true,
+	"VND": true,
+}
+
+// ValidateCurrency checks the code is a supported upper-case ISO 4217 code.
+func ValidateCurrency(code string) error {
+	if code == "" {
+		return fmt.Errorf("currency is required")
+	}
+	if !currencies[code] {
+		return fmt.Errorf("unsupported currency %q", code)
+	}
+	return nil
+}
+
+// Normalize upper-cases and trims a currency code supplied by a client.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
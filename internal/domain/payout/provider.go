@@ -0,0 +1,30 @@
+package payout
+
+import (
+	"context"
+	"errors"
+)
+
+// Request is a single payout instruction sent to a payment provider.
+// Amount is expressed in minor units of Currency.
+type Request struct {
+	IdempotencyKey string
+	CampaignID     int64
+	UserID         string
+	Amount         int64
+	Currency       string
+}
+
+// Result is returned by a provider after a successful payout.
+type Result struct {
+	Reference string
+}
+
+// Provider moves claimed money into a user's wallet. Implementations must
+// treat repeated calls with the same IdempotencyKey as the same payout.
+type Provider interface {
+	Pay(ctx context.Context, req Request) (Result, error)
+}
+
+// ErrPermanent marks provider failures that must not be retried.
+var ErrPermanent = errors.New("permanent payout failure")
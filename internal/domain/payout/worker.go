@@ -0,0 +1,131 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"redpacket/internal/db"
+	"redpacket/internal/observability/logging"
+	"redpacket/internal/observability/metrics"
+)
+
+// WorkerConfig tunes polling and retry behaviour.
+type WorkerConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	CallTimeout  time.Duration
+}
+
+// Store persists the payout queue; *db.Store implements it.
+type Store interface {
+	LeaseDuePayouts(ctx context.Context, limit int, lease time.Duration) ([]db.Payout, error)
+	MarkPayoutSucceeded(ctx context.Context, id int64, providerRef string) error
+	MarkPayoutRetrying(ctx context.Context, id int64, delay time.Duration, lastErr string) error
+	MarkPayoutFailed(ctx context.Context, id int64, lastErr string) error
+}
+
+// Worker delivers pending payouts to a Provider.
+type Worker struct {
+	store    Store
+	provider Provider
+	cfg      WorkerConfig
+	logger   *slog.Logger
+}
+
+// NewWorker builds a Worker.
+func NewWorker(store Store, provider Provider, cfg WorkerConfig, logger *slog.Logger) *Worker {
+	return &Worker{store: store, provider: provider, cfg: cfg, logger: logger.With(slog.String("component", "payout_worker"))}
+}
+
+// Run polls for due payouts until ctx is canceled.
+func (w *Worker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.logger.ErrorContext(ctx, "payout pass failed", logging.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce processes batches until no due payout is left.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	// A leased payout is retried by the next poll if the worker dies mid-call,
+	// so the lease only needs to outlive one provider call.
+	lease := w.cfg.CallTimeout * 2
+	for {
+		batch, err := w.store.LeaseDuePayouts(ctx, w.cfg.BatchSize, lease)
+		if err != nil {
+			return err
+		}
+		for _, p := range batch {
+			w.process(ctx, p)
+		}
+		if len(batch) < w.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+func (w *Worker) process(ctx context.Context, p db.Payout) {
+	start := time.Now()
+	defer func() { metrics.ObserveConsumerProcessing("payout", time.Since(start)) }()
+	ctx = logging.With(ctx, slog.Int64("payout_id", p.ID), logging.CampaignID(p.CampaignID), logging.UserID(p.UserID))
+
+	callCtx, cancel := context.WithTimeout(ctx, w.cfg.CallTimeout)
+	res, err := w.provider.Pay(callCtx, Request{
+		IdempotencyKey: p.IdempotencyKey,
+		CampaignID:     p.CampaignID,
+		UserID:         p.UserID,
+		Amount:         p.Amount,
+		Currency:       p.Currency,
+	})
+	cancel()
+
+	if err == nil {
+		if err := w.store.MarkPayoutSucceeded(ctx, p.ID, res.Reference); err != nil {
+			w.logger.ErrorContext(ctx, "failed to mark payout succeeded", logging.Err(err))
+		}
+		return
+	}
+	if errors.Is(err, ErrPermanent) || p.Attempts >= w.cfg.MaxAttempts {
+		w.logger.WarnContext(ctx, "payout failed", slog.Int("attempts", p.Attempts), logging.Err(err))
+		if err := w.store.MarkPayoutFailed(ctx, p.ID, err.Error()); err != nil {
+			w.logger.ErrorContext(ctx, "failed to mark payout failed", logging.Err(err))
+		}
+		return
+	}
+	if err := w.store.MarkPayoutRetrying(ctx, p.ID, w.backoff(p.Attempts), err.Error()); err != nil {
+		w.logger.ErrorContext(ctx, "failed to schedule payout retry", logging.Err(err))
+	}
+}
+
+// backoff doubles the delay per attempt, caps it and adds up to 20% jitter.
+func (w *Worker) backoff(attempt int) time.Duration {
+	delay := w.cfg.BaseBackoff
+	for i := 1; i < attempt && delay < w.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.cfg.MaxBackoff {
+		delay = w.cfg.MaxBackoff
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// IdempotencyKey derives the payout key for a claim. A user can win at most
+// once per campaign, so redelivered claim events map to the same payout.
+func IdempotencyKey(campaignID int64, userID string) string {
+	return fmt.Sprintf("payout:%d:%s", campaignID, userID)
+}
@@ -0,0 +1,234 @@
+package payout_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"redpacket/internal/db"
+	"redpacket/internal/domain/payout"
+	"redpacket/internal/paymentprovider"
+)
+
+// memStore is a payout.Store that follows the SQL in db/payout.go against a
+// clock the test moves by hand.
+type memStore struct {
+	mu      sync.Mutex
+	now     time.Time
+	rows    map[int64]*memPayout
+	failAck error
+}
+
+type memPayout struct {
+	payout      db.Payout
+	nextAttempt time.Time
+	providerRef string
+	lastErr     string
+	delays      []time.Duration
+}
+
+func newMemStore(payouts ...db.Payout) *memStore {
+	s := &memStore{now: time.Unix(1_700_000_000, 0), rows: make(map[int64]*memPayout)}
+	for _, p := range payouts {
+		p.Status = "pending"
+		s.rows[p.ID] = &memPayout{payout: p, nextAttempt: s.now}
+	}
+	return s
+}
+
+func (s *memStore) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+func (s *memStore) get(id int64) memPayout {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.rows[id]
+}
+
+func (s *memStore) LeaseDuePayouts(_ context.Context, limit int, lease time.Duration) ([]db.Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []db.Payout
+	for id := int64(1); len(items) < limit && id <= int64(len(s.rows)); id++ {
+		row := s.rows[id]
+		if (row.payout.Status != "pending" && row.payout.Status != "retrying") || row.nextAttempt.After(s.now) {
+			continue
+		}
+		row.payout.Attempts++
+		row.nextAttempt = s.now.Add(lease)
+		items = append(items, row.payout)
+	}
+	return items, nil
+}
+
+func (s *memStore) MarkPayoutSucceeded(_ context.Context, id int64, providerRef string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.failAck; err != nil {
+		s.failAck = nil
+		return err
+	}
+	s.rows[id].payout.Status = "succeeded"
+	s.rows[id].providerRef = providerRef
+	return nil
+}
+
+func (s *memStore) MarkPayoutRetrying(_ context.Context, id int64, delay time.Duration, lastErr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row := s.rows[id]
+	row.payout.Status = "retrying"
+	row.nextAttempt = s.now.Add(delay)
+	row.lastErr = lastErr
+	row.delays = append(row.delays, delay)
+	return nil
+}
+
+func (s *memStore) MarkPayoutFailed(_ context.Context, id int64, lastErr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows[id].payout.Status = "failed"
+	s.rows[id].lastErr = lastErr
+	return nil
+}
+
+var testConfig = payout.WorkerConfig{
+	BatchSize:   10,
+	MaxAttempts: 4,
+	BaseBackoff: time.Second,
+	MaxBackoff:  4 * time.Second,
+	CallTimeout: time.Second,
+}
+
+func newTestWorker(t *testing.T, store payout.Store, mock *paymentprovider.MockServer) *payout.Worker {
+	t.Helper()
+	srv := httptest.NewServer(mock)
+	t.Cleanup(srv.Close)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return payout.NewWorker(store, paymentprovider.NewClient(srv.URL), testConfig, logger)
+}
+
+func testPayout(id int64, userID string, amount int64) db.Payout {
+	return db.Payout{ID: id, CampaignID: 7, UserID: userID, Amount: amount, Currency: "USD", IdempotencyKey: payout.IdempotencyKey(7, userID)}
+}
+
+func TestWorkerPaysDuePayouts(t *testing.T) {
+	store := newMemStore(testPayout(1, "alice", 100), testPayout(2, "bob", 200))
+	w := newTestWorker(t, store, paymentprovider.NewMockServer(0, 0))
+
+	if err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	for id, want := range map[int64]string{1: "mock-1", 2: "mock-2"} {
+		row := store.get(id)
+		if row.payout.Status != "succeeded" || row.providerRef != want || row.payout.Attempts != 1 {
+			t.Fatalf("payout %d = %s ref %q after %d attempts, want succeeded ref %q after 1", id, row.payout.Status, row.providerRef, row.payout.Attempts, want)
+		}
+	}
+}
+
+func TestWorkerRetriesTransientFailuresWithBackoff(t *testing.T) {
+	store := newMemStore(testPayout(1, "alice", 100))
+	w := newTestWorker(t, store, paymentprovider.NewMockServer(1, 0))
+
+	// The delay doubles per attempt, is capped at MaxBackoff and carries up
+	// to 20% jitter.
+	wantBase := []time.Duration{time.Second, 2 * time.Second}
+	for attempt := 1; attempt < testConfig.MaxAttempts; attempt++ {
+		if err := w.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		row := store.get(1)
+		if row.payout.Status != "retrying" || !strings.Contains(row.lastErr, "503") {
+			t.Fatalf("attempt %d: status %s, last error %q, want retrying on 503", attempt, row.payout.Status, row.lastErr)
+		}
+		delay := row.delays[attempt-1]
+		base := testConfig.MaxBackoff
+		if attempt <= len(wantBase) {
+			base = wantBase[attempt-1]
+		}
+		if delay < base || delay > base+base/5 {
+			t.Fatalf("attempt %d: delay %v, want within [%v, %v]", attempt, delay, base, base+base/5)
+		}
+
+		// Nothing is due until the delay has passed.
+		store.advance(delay - time.Millisecond)
+		if err := w.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		if got := store.get(1).payout.Attempts; got != attempt {
+			t.Fatalf("attempts = %d before the delay passed, want %d", got, attempt)
+		}
+		store.advance(time.Millisecond)
+	}
+
+	if err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if row := store.get(1); row.payout.Status != "failed" || row.payout.Attempts != testConfig.MaxAttempts {
+		t.Fatalf("payout = %s after %d attempts, want failed after %d", row.payout.Status, row.payout.Attempts, testConfig.MaxAttempts)
+	}
+}
+
+func TestWorkerFailsPermanentErrorsImmediately(t *testing.T) {
+	store := newMemStore(testPayout(1, "alice", 0))
+	w := newTestWorker(t, store, paymentprovider.NewMockServer(0, 0))
+
+	if err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	row := store.get(1)
+	if row.payout.Status != "failed" || row.payout.Attempts != 1 || len(row.delays) != 0 {
+		t.Fatalf("payout = %s after %d attempts and %d retries, want failed after 1 and none", row.payout.Status, row.payout.Attempts, len(row.delays))
+	}
+	if !strings.Contains(row.lastErr, "422") {
+		t.Fatalf("last error = %q, want the provider's 422", row.lastErr)
+	}
+}
+
+func TestWorkerRepaysExpiredLeaseIdempotently(t *testing.T) {
+	store := newMemStore(testPayout(1, "alice", 100))
+	w := newTestWorker(t, store, paymentprovider.NewMockServer(0, 0))
+
+	// The provider pays, but the worker loses the result, as if it died
+	// before recording it.
+	store.failAck = errors.New("connection reset")
+	if err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := store.get(1).payout.Status; got != "pending" {
+		t.Fatalf("status = %s after a lost ack, want pending", got)
+	}
+
+	// The lease keeps other passes off the payout until it expires.
+	lease := testConfig.CallTimeout * 2
+	store.advance(lease - time.Millisecond)
+	if err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got := store.get(1).payout.Attempts; got != 1 {
+		t.Fatalf("attempts = %d while leased, want 1", got)
+	}
+
+	store.advance(time.Millisecond)
+	if err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	row := store.get(1)
+	if row.payout.Status != "succeeded" || row.payout.Attempts != 2 {
+		t.Fatalf("payout = %s after %d attempts, want succeeded after 2", row.payout.Status, row.payout.Attempts)
+	}
+	// The same idempotency key returns the first payment instead of a second.
+	if row.providerRef != "mock-1" {
+		t.Fatalf("provider ref = %q, want the first payment mock-1", row.providerRef)
+	}
+}
@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func TestCronNext(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	tests := []struct {
+		name  string
+		cron  string
+		loc   *time.Location
+		after string
+		want  string // RFC 3339, or "" for no match within the limit
+	}{
+		// Steps and ranges.
+		{"every 15 minutes", "*/15 * * * *", time.UTC, "2025-06-10T10:07:00Z", "2025-06-10T10:15:00Z"},
+		{"strictly after a match", "*/15 * * * *", time.UTC, "2025-06-10T10:15:00Z", "2025-06-10T10:30:00Z"},
+		{"seconds are truncated", "*/15 * * * *", time.UTC, "2025-06-10T10:14:59Z", "2025-06-10T10:15:00Z"},
+		{"stepped range", "0-30/10 9 * * *", time.UTC, "2025-06-10T09:25:00Z", "2025-06-10T09:30:00Z"},
+		{"stepped range wraps to next day", "0-30/10 9 * * *", time.UTC, "2025-06-10T09:30:00Z", "2025-06-11T09:00:00Z"},
+		{"step from a start value", "5/20 * * * *", time.UTC, "2025-06-10T10:26:00Z", "2025-06-10T10:45:00Z"},
+		{"list", "0 8,20 * * *", time.UTC, "2025-06-10T08:00:00Z", "2025-06-10T20:00:00Z"},
+		{"month names", "0 0 1 jan,jul *", time.UTC, "2025-02-01T00:00:00Z", "2025-07-01T00:00:00Z"},
+		{"weekday range by name", "0 12 * * mon-fri", time.UTC, "2025-06-07T00:00:00Z", "2025-06-09T12:00:00Z"},
+		{"7 is Sunday", "0 0 * * 7", time.UTC, "2025-06-07T00:00:00Z", "2025-06-08T00:00:00Z"},
+		{"macro", "@monthly", time.UTC, "2025-06-10T00:00:00Z", "2025-07-01T00:00:00Z"},
+
+		// Day of month and day of week.
+		{"day of month only", "0 0 13 * *", time.UTC, "2025-06-01T00:00:00Z", "2025-06-13T00:00:00Z"},
+		{"day of week only", "0 0 * * 1", time.UTC, "2025-06-10T00:00:00Z", "2025-06-16T00:00:00Z"},
+		{"union picks day of month first", "0 0 13 * 1", time.UTC, "2025-06-10T00:00:00Z", "2025-06-13T00:00:00Z"},
+		{"union picks day of week first", "0 0 13 * 1", time.UTC, "2025-06-13T00:00:00Z", "2025-06-16T00:00:00Z"},
+		{"starred day of week intersects", "0 0 13 * */2", time.UTC, "2025-06-01T00:00:00Z", "2025-07-13T00:00:00Z"},
+
+		// DST in New York: 2025-03-09 02:00 EST jumps to 03:00 EDT, and
+		// 2025-11-02 02:00 EDT falls back to 01:00 EST.
+		{"nonexistent time is skipped", "30 2 * * *", newYork, "2025-03-08T03:00:00-05:00", "2025-03-10T02:30:00-04:00"},
+		{"hourly across spring forward", "0 * * * *", newYork, "2025-03-09T01:30:00-05:00", "2025-03-09T03:00:00-04:00"},
+		{"repeated time fires first", "30 1 * * *", newYork, "2025-11-02T00:00:00-04:00", "2025-11-02T01:30:00-04:00"},
+		{"repeated time fires once", "30 1 * * *", newYork, "2025-11-02T01:30:00-04:00", "2025-11-03T01:30:00-05:00"},
+		{"hourly across fall back", "0 * * * *", newYork, "2025-11-02T01:00:00-04:00", "2025-11-02T02:00:00-05:00"},
+		{"daily keeps wall clock across fall back", "0 9 * * *", newYork, "2025-11-01T09:00:00-04:00", "2025-11-02T09:00:00-05:00"},
+
+		// Five-year limit.
+		{"leap day within the limit", "0 0 29 2 *", time.UTC, "2025-03-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"impossible date", "0 0 30 2 *", time.UTC, "2025-01-01T00:00:00Z", ""},
+		// 2100 is not a leap year, so the next leap day is eight years away.
+		{"leap day beyond the limit", "0 0 29 2 *", time.UTC, "2096-03-01T00:00:00Z", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ParseCron(tc.cron)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tc.cron, err)
+			}
+			after, err := time.Parse(time.RFC3339, tc.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := c.Next(after, tc.loc)
+			if tc.want == "" {
+				if !got.IsZero() {
+					t.Fatalf("Next(%s) = %s, want none", tc.after, got.Format(time.RFC3339))
+				}
+				return
+			}
+			want, err := time.Parse(time.RFC3339, tc.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(want) {
+				t.Fatalf("Next(%s) = %s, want %s", tc.after, got.Format(time.RFC3339), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseCronRejects(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@yearly",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"redpacket/internal/db"
+	"redpacket/internal/domain/campaign"
+	"redpacket/internal/observability/logging"
+	redisClient "redpacket/internal/redis"
+)
+
+// LeaderLockKey is the Redis lease lock that elects the replica running the scheduler.
+const LeaderLockKey = "scheduler:leader"
+
+// maxOccurrencesPerPass bounds how many campaigns one schedule may create in a pass.
+const maxOccurrencesPerPass = 100
+
+// Config tunes the scheduler loop.
+type Config struct {
+	// Interval between passes.
+	Interval time.Duration
+	// Lookahead is how far ahead occurrences are turned into campaigns.
+	Lookahead time.Duration
+	// LockTTL is the leader lease; it must outlive Interval so the leader keeps it.
+	LockTTL time.Duration
+}
+
+// Scheduler materializes upcoming occurrences of enabled schedules as
+// campaigns. Only the replica holding LeaderLockKey runs a pass, and the
+// unique (schedule_id, occurrence) constraint rejects any duplicate that slips
+// through a lease handover.
+type Scheduler struct {
+	store     *db.Store
+	redis     *redisClient.Client
+	campaigns *campaign.Service
+	cfg       Config
+	owner     string
+	logger    *slog.Logger
+}
+
+// NewScheduler builds a Scheduler.
+func NewScheduler(store *db.Store, redis *redisClient.Client, campaigns *campaign.Service, cfg Config, logger *slog.Logger) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		store:     store,
+		redis:     redis,
+		campaigns: campaigns,
+		cfg:       cfg,
+		owner:     fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano()),
+		logger:    logger.With(slog.String("component", "scheduler")),
+	}
+}
+
+// Run executes passes while this replica is the leader until ctx is canceled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.redis.ReleaseLock(releaseCtx, LeaderLockKey, s.owner); err != nil {
+			s.logger.WarnContext(releaseCtx, "failed to release leader lock", logging.Err(err))
+		}
+	}()
+	for {
+		leader, err := s.redis.AcquireLock(ctx, LeaderLockKey, s.owner, s.cfg.LockTTL)
+		if err != nil && ctx.Err() == nil {
+			s.logger.ErrorContext(ctx, "failed to acquire leader lock", logging.Err(err))
+		}
+		if leader {
+			if err := s.RunOnce(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				s.logger.ErrorContext(ctx, "scheduler pass failed", logging.Err(err))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce creates campaigns for every occurrence of an enabled schedule that
+// starts within the lookahead of now and has no campaign yet. Occurrences in
+// the past are never backfilled.
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) error {
+	schedules, err := s.store.ListCampaignSchedules(ctx, true)
+	if err != nil {
+		return err
+	}
+	horizon := now.Add(s.cfg.Lookahead)
+	for _, sc := range schedules {
+		cron, loc, err := parse(sc.Cron, sc.Timezone)
+		if err != nil {
+			s.logger.WarnContext(ctx, "skipping schedule", slog.Int64("schedule_id", sc.ID), logging.Err(err))
+			continue
+		}
+		created, err := s.store.ListScheduleOccurrences(ctx, sc.ID, now)
+		if err != nil {
+			return err
+		}
+		count := 0
+		for t := cron.Next(now, loc); !t.IsZero() && !t.After(horizon); t = cron.Next(t, loc) {
+			if count == maxOccurrencesPerPass {
+				s.logger.WarnContext(ctx, "too many occurrences in the lookahead, deferring the rest", slog.Int64("schedule_id", sc.ID), slog.Int("max_occurrences", maxOccurrencesPerPass))
+				break
+			}
+			count++
+			if _, ok := created[t.UTC()]; ok {
+				continue
+			}
+			id, err := s.campaigns.CreateScheduledCampaign(ctx, sc.ID, sc.TemplateID, t)
+			if errors.Is(err, db.ErrOccurrenceExists) {
+				continue
+			}
+			if err != nil {
+				s.logger.ErrorContext(ctx, "failed to create scheduled campaign", slog.Int64("schedule_id", sc.ID), slog.Time("occurrence", t), logging.Err(err))
+				break
+			}
+			s.logger.InfoContext(ctx, "created scheduled campaign", logging.CampaignID(id), slog.Int64("schedule_id", sc.ID), slog.Time("occurrence", t))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/db"
+	"redpacket/internal/domain/campaign"
+)
+
+var (
+	// ErrScheduleNotFound indicates the schedule is missing.
+	ErrScheduleNotFound = errors.New("campaign schedule not found")
+	// ErrInvalidSchedule indicates a bad cron expression, timezone or template.
+	ErrInvalidSchedule = errors.New("invalid campaign schedule")
+)
+
+// Input captures a recurring schedule. Each occurrence of Cron, evaluated in
+// Timezone (an IANA name, default UTC), starts a campaign from the template.
+type Input struct {
+	Name       string
+	TemplateID int64
+	Cron       string
+	Timezone   string
+	Enabled    bool
+}
+
+// Service manages campaign schedules.
+type Service struct {
+	store     *db.Store
+	campaigns *campaign.Service
+}
+
+// NewService wires dependencies.
+func NewService(store *db.Store, campaigns *campaign.Service) *Service {
+	return &Service{store: store, campaigns: campaigns}
+}
+
+// Create validates and stores a schedule.
+func (s *Service) Create(ctx context.Context, in Input) (int64, error) {
+	if in.Name == "" {
+		return 0, fmt.Errorf("%w: name is required", ErrInvalidSchedule)
+	}
+	if in.Timezone == "" {
+		in.Timezone = "UTC"
+	}
+	if _, _, err := parse(in.Cron, in.Timezone); err != nil {
+		return 0, err
+	}
+	if _, err := s.campaigns.GetTemplate(ctx, in.TemplateID); err != nil {
+		if errors.Is(err, campaign.ErrTemplateNotFound) {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+		}
+		return 0, err
+	}
+	return s.store.InsertCampaignSchedule(ctx, db.CampaignSchedule{
+		Name:       in.Name,
+		TemplateID: in.TemplateID,
+		Cron:       in.Cron,
+		Timezone:   in.Timezone,
+		Enabled:    in.Enabled,
+	})
+}
+
+// Get returns a schedule by id.
+func (s *Service) Get(ctx context.Context, id int64) (*db.CampaignSchedule, error) {
+	sc, err := s.store.GetCampaignSchedule(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrScheduleNotFound
+	}
+	return sc, err
+}
+
+// List returns every schedule.
+func (s *Service) List(ctx context.Context) ([]db.CampaignSchedule, error) {
+	return s.store.ListCampaignSchedules(ctx, false)
+}
+
+// SetEnabled pauses or resumes a schedule. Campaigns already created are kept.
+func (s *Service) SetEnabled(ctx context.Context, id int64, enabled bool) error {
+	err := s.store.SetCampaignScheduleEnabled(ctx, id, enabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrScheduleNotFound
+	}
+	return err
+}
+
+// Upcoming returns the next n occurrences of the schedule after from.
+func Upcoming(sc db.CampaignSchedule, from time.Time, n int) ([]time.Time, error) {
+	cron, loc, err := parse(sc.Cron, sc.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, 0, n)
+	for t := cron.Next(from, loc); !t.IsZero() && len(times) < n; t = cron.Next(t, loc) {
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+func parse(expr, timezone string) (*Cron, *time.Location, error) {
+	cron, err := ParseCron(expr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, timezone)
+	}
+	return cron, loc, nil
+}
@@ -0,0 +1,22 @@
+package settlement
+
+import "time"
+
+// TierEvent is the per-tier part of a settlement event.
+type TierEvent struct {
+	Amount         int64 `json:"amount"`
+	InitialTotal   int   `json:"initial_total"`
+	ClaimedCount   int   `json:"claimed_count"`
+	UnclaimedCount int   `json:"unclaimed_count"`
+}
+
+// Event is emitted once a campaign has been settled. Amounts are in minor units of Currency.
+type Event struct {
+	CampaignID     int64       `json:"campaign_id"`
+	Currency       string      `json:"currency"`
+	FundedTotal    int64       `json:"funded_total"`
+	ClaimedTotal   int64       `json:"claimed_total"`
+	UnclaimedTotal int64       `json:"unclaimed_total"`
+	Tiers          []TierEvent `json:"tiers"`
+	SettledAt      time.Time   `json:"settled_at"`
+}
@@ -0,0 +1,180 @@
+package settlement
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/db"
+	"redpacket/internal/domain/leaderboard"
+	"redpacket/internal/observability/logging"
+	redisClient "redpacket/internal/redis"
+)
+
+// Publisher emits settlement events.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// ClaimBacklog reports whether every claim event sent up to a time has been
+// consumed into claim_log.
+type ClaimBacklog interface {
+	DrainedThrough(ctx context.Context, t time.Time) (bool, error)
+}
+
+// Config tunes the settlement job.
+type Config struct {
+	// Interval between settlement passes.
+	Interval time.Duration
+	// Delay after end_time before settling. Campaigns are also held until
+	// every claim event sent up to end_time + Delay has been consumed.
+	Delay time.Duration
+	// RedisGrace is the TTL given to a settled campaign's Redis keys.
+	RedisGrace time.Duration
+	// BatchSize caps the campaigns handled per pass.
+	BatchSize int
+}
+
+// Job settles ended campaigns.
+type Job struct {
+	store     *db.Store
+	redis     *redisClient.Client
+	publisher Publisher
+	claims    ClaimBacklog
+	cfg       Config
+	logger    *slog.Logger
+}
+
+// NewJob builds a Job.
+func NewJob(store *db.Store, redis *redisClient.Client, publisher Publisher, claims ClaimBacklog, cfg Config, logger *slog.Logger) *Job {
+	return &Job{store: store, redis: redis, publisher: publisher, claims: claims, cfg: cfg, logger: logger.With(slog.String("component", "settlement_job"))}
+}
+
+// Run settles campaigns periodically until ctx is canceled.
+func (j *Job) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			j.logger.ErrorContext(ctx, "settlement pass failed", logging.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce advances campaign statuses, settles due campaigns and completes
+// follow-ups left by earlier passes.
+func (j *Job) RunOnce(ctx context.Context) error {
+	if _, err := j.store.AdvanceCampaignStatuses(ctx); err != nil {
+		return err
+	}
+	if err := j.settleDue(ctx); err != nil {
+		return err
+	}
+
+	pending, err := j.store.ListSettlementsPendingFollowUp(ctx, j.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, st := range pending {
+		j.followUp(ctx, st)
+	}
+	return nil
+}
+
+// settleDue settles the campaigns that ended before the cutoff, once every
+// claim event sent up to it has been consumed. Their claims were all made
+// before they ended, so claim_log then holds every one of them.
+func (j *Job) settleDue(ctx context.Context) error {
+	cutoff := time.Now().Add(-j.cfg.Delay)
+	drained, err := j.claims.DrainedThrough(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	if !drained {
+		j.logger.InfoContext(ctx, "claim events are still being consumed, postponing settlement", slog.Time("cutoff", cutoff))
+		return nil
+	}
+	due, err := j.store.ListCampaignsDueForSettlement(ctx, cutoff, j.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, campaignID := range due {
+		if err := j.settle(ctx, campaignID); err != nil {
+			j.logger.ErrorContext(ctx, "failed to settle campaign", logging.CampaignID(campaignID), logging.Err(err))
+		}
+	}
+	return nil
+}
+
+func (j *Job) settle(ctx context.Context, campaignID int64) error {
+	frozen, err := j.redis.FreezeCampaign(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	if !frozen {
+		// Nothing can be claimed without a window key.
+		j.logger.InfoContext(ctx, "campaign has no Redis window, skipping freeze", logging.CampaignID(campaignID))
+	}
+	err = j.store.RunInTx(ctx, func(tx pgx.Tx) error {
+		st, err := j.store.SettleCampaignTx(ctx, tx, campaignID)
+		if err != nil {
+			return err
+		}
+		if _, err := j.store.SnapshotLeaderboardTx(ctx, tx, campaignID, leaderboard.MaxLimit); err != nil {
+			return err
+		}
+		j.logger.InfoContext(ctx, "settled campaign", logging.CampaignID(campaignID),
+			slog.Int64("claimed", st.ClaimedTotal), slog.Int64("unclaimed", st.UnclaimedTotal), slog.String("currency", st.Currency))
+		return nil
+	})
+	if errors.Is(err, db.ErrAlreadySettled) {
+		return nil
+	}
+	return err
+}
+
+func (j *Job) followUp(ctx context.Context, st db.Settlement) {
+	if st.EventPublishedAt == nil {
+		if err := j.publisher.Publish(ctx, toEvent(st)); err != nil {
+			j.logger.ErrorContext(ctx, "failed to publish settlement event", logging.CampaignID(st.CampaignID), logging.Err(err))
+		} else if err := j.store.MarkSettlementEventPublished(ctx, st.CampaignID); err != nil {
+			j.logger.ErrorContext(ctx, "failed to mark settlement event published", logging.CampaignID(st.CampaignID), logging.Err(err))
+		}
+	}
+	if st.RedisReleasedAt == nil {
+		if err := j.redis.ExpireCampaignKeys(ctx, st.CampaignID, j.cfg.RedisGrace); err != nil {
+			j.logger.ErrorContext(ctx, "failed to expire redis keys", logging.CampaignID(st.CampaignID), logging.Err(err))
+		} else if err := j.store.MarkSettlementRedisReleased(ctx, st.CampaignID); err != nil {
+			j.logger.ErrorContext(ctx, "failed to mark redis released", logging.CampaignID(st.CampaignID), logging.Err(err))
+		}
+	}
+}
+
+func toEvent(st db.Settlement) Event {
+	event := Event{
+		CampaignID:     st.CampaignID,
+		Currency:       st.Currency,
+		FundedTotal:    st.FundedTotal,
+		ClaimedTotal:   st.ClaimedTotal,
+		UnclaimedTotal: st.UnclaimedTotal,
+		SettledAt:      st.SettledAt,
+		Tiers:          make([]TierEvent, 0, len(st.Tiers)),
+	}
+	for _, t := range st.Tiers {
+		event.Tiers = append(event.Tiers, TierEvent{
+			Amount:         t.Amount,
+			InitialTotal:   t.InitialTotal,
+			ClaimedCount:   t.ClaimedCount,
+			UnclaimedCount: t.UnclaimedCount,
+		})
+	}
+	return event
+}
@@ -0,0 +1,253 @@
+package waitroom
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"redpacket/internal/db"
+	"redpacket/internal/observability/metrics"
+	redisClient "redpacket/internal/redis"
+)
+
+// roomCacheTTL is how long a campaign's admission settings are reused before
+// being read from Postgres again.
+const roomCacheTTL = 30 * time.Second
+
+// maxCachedRooms bounds the settings cache, which is keyed by whatever
+// campaign ids clients send.
+const maxCachedRooms = 10000
+
+// ErrCampaignNotFound indicates the campaign is missing.
+var ErrCampaignNotFound = errors.New("campaign not found")
+
+// ErrNoWaitingRoom indicates the campaign admits everyone without a queue.
+var ErrNoWaitingRoom = errors.New("campaign has no waiting room")
+
+// ErrInvalidToken indicates a missing, forged or foreign queue token.
+var ErrInvalidToken = errors.New("invalid queue token")
+
+// ErrNotAdmitted indicates the token's turn has not come yet.
+var ErrNotAdmitted = errors.New("not admitted yet")
+
+// Status is a user's place in a campaign's waiting room. Position counts the
+// tickets still waiting ahead of and including this one, and is 0 once
+// admitted. ETA is the time left until admission.
+type Status struct {
+	Token    string
+	Ticket   int64
+	Position int64
+	Admitted bool
+	ETA      time.Duration
+}
+
+// Service issues waiting room tickets and checks admission. Each campaign
+// with an admission rate admits the first rate tickets at its start and rate
+// more every second after that, so admission needs only the ticket, which is
+// carried in an HMAC-signed token, and the clock.
+type Service struct {
+	store  *db.Store
+	redis  *redisClient.Client
+	secret []byte
+
+	mu    sync.Mutex
+	rooms map[int64]room
+}
+
+type room struct {
+	found     bool
+	rate      int
+	start     time.Time
+	expiresAt time.Time
+}
+
+// NewService wires dependencies. Tokens are signed with secret, which must be
+// shared by every API instance.
+func NewService(store *db.Store, redis *redisClient.Client, secret []byte) *Service {
+	return &Service{store: store, redis: redis, secret: secret, rooms: make(map[int64]room)}
+}
+
+// Join puts the user in the campaign's waiting room, or returns their
+// existing place, with a token for /open.
+func (s *Service) Join(ctx context.Context, campaignID int64, userID string) (*Status, error) {
+	if userID == "" {
+		return nil, errors.New("user id required")
+	}
+	r, err := s.room(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if r.rate == 0 {
+		return nil, ErrNoWaitingRoom
+	}
+	ticket, err := s.redis.IssueQueueTicket(ctx, campaignID, userID)
+	if err != nil {
+		return nil, err
+	}
+	metrics.IncWaitingRoomJoin()
+	status := r.status(ticket, time.Now())
+	status.Token = s.sign(campaignID, userID, ticket)
+	return status, nil
+}
+
+// Status reports the place of a token issued by Join.
+func (s *Service) Status(ctx context.Context, campaignID int64, token string) (*Status, error) {
+	r, err := s.room(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if r.rate == 0 {
+		return nil, ErrNoWaitingRoom
+	}
+	_, ticket, err := s.verify(campaignID, token)
+	if err != nil {
+		return nil, err
+	}
+	status := r.status(ticket, time.Now())
+	status.Token = token
+	return status, nil
+}
+
+// Depth returns how many of the campaign's tickets are issued but not yet
+// admitted, and its admit rate. It returns ErrNoWaitingRoom for campaigns
+// without a waiting room.
+func (s *Service) Depth(ctx context.Context, campaignID int64) (int64, int, error) {
+	r, err := s.room(ctx, campaignID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if r.rate == 0 {
+		return 0, 0, ErrNoWaitingRoom
+	}
+	issued, err := s.redis.QueueTicketsIssued(ctx, campaignID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return max(issued-r.admitted(time.Now()), 0), r.rate, nil
+}
+
+// Admit checks that the user may open the campaign. It returns nil for
+// campaigns without a waiting room, ErrInvalidToken if the token is not the
+// user's, and ErrNotAdmitted with the user's status if their turn has not
+// come yet. Missing campaigns are let through, so /open can report them.
+func (s *Service) Admit(ctx context.Context, campaignID int64, userID, token string) (*Status, error) {
+	r, err := s.room(ctx, campaignID)
+	if errors.Is(err, ErrCampaignNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.rate == 0 {
+		return nil, nil
+	}
+	tokenUser, ticket, err := s.verify(campaignID, token)
+	if err != nil || tokenUser != userID {
+		metrics.IncWaitingRoomCheck("invalid")
+		return nil, ErrInvalidToken
+	}
+	status := r.status(ticket, time.Now())
+	if !status.Admitted {
+		metrics.IncWaitingRoomCheck("waiting")
+		return status, ErrNotAdmitted
+	}
+	metrics.IncWaitingRoomCheck("admitted")
+	return nil, nil
+}
+
+// admitted returns how many tickets are admitted at now.
+func (r room) admitted(now time.Time) int64 {
+	if now.Before(r.start) {
+		return 0
+	}
+	return int64(r.rate) + int64(now.Sub(r.start).Seconds()*float64(r.rate))
+}
+
+func (r room) status(ticket int64, now time.Time) *Status {
+	admitAt := r.start
+	if ticket > int64(r.rate) {
+		admitAt = admitAt.Add(time.Duration(float64(ticket-int64(r.rate)) / float64(r.rate) * float64(time.Second)))
+	}
+	status := &Status{Ticket: ticket, Admitted: !now.Before(admitAt)}
+	if !status.Admitted {
+		status.Position = ticket - r.admitted(now)
+		status.ETA = admitAt.Sub(now)
+	}
+	return status
+}
+
+// room returns the campaign's admission settings, cached for roomCacheTTL.
+// A zero rate means no waiting room.
+func (s *Service) room(ctx context.Context, campaignID int64) (room, error) {
+	now := time.Now()
+	s.mu.Lock()
+	r, ok := s.rooms[campaignID]
+	s.mu.Unlock()
+	if !ok || !now.Before(r.expiresAt) {
+		c, err := s.store.GetCampaign(ctx, campaignID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return room{}, err
+		}
+		r = room{found: err == nil, expiresAt: now.Add(roomCacheTTL)}
+		if c != nil && c.AdmissionRate != nil {
+			r.rate = *c.AdmissionRate
+			r.start = c.StartTime
+		}
+		s.mu.Lock()
+		if len(s.rooms) >= maxCachedRooms {
+			s.rooms = make(map[int64]room)
+		}
+		s.rooms[campaignID] = r
+		s.mu.Unlock()
+	}
+	if !r.found {
+		return room{}, ErrCampaignNotFound
+	}
+	return r, nil
+}
+
+// Tokens read <campaign>.<user>.<ticket>.<mac>, with the user id and the MAC
+// base64url encoded.
+
+func (s *Service) sign(campaignID int64, userID string, ticket int64) string {
+	payload := fmt.Sprintf("%d.%s.%d", campaignID, base64.RawURLEncoding.EncodeToString([]byte(userID)), ticket)
+	return payload + "." + s.mac(payload)
+}
+
+func (s *Service) verify(campaignID int64, token string) (string, int64, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return "", 0, ErrInvalidToken
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(s.mac(payload))) {
+		return "", 0, ErrInvalidToken
+	}
+	if parts[0] != strconv.FormatInt(campaignID, 10) {
+		return "", 0, ErrInvalidToken
+	}
+	userID, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", 0, ErrInvalidToken
+	}
+	ticket, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || ticket < 1 {
+		return "", 0, ErrInvalidToken
+	}
+	return string(userID), ticket, nil
+}
+
+func (s *Service) mac(payload string) string {
+	m := hmac.New(sha256.New, s.secret)
+	m.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
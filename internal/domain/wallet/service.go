@@ -0,0 +1,75 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"redpacket/internal/db"
+)
+
+const (
+	// DefaultPageSize is used when the caller does not ask for a page size.
+	DefaultPageSize = 20
+	// MaxPageSize caps a single claim history page.
+	MaxPageSize = 100
+)
+
+// ErrInvalidCursor indicates the pagination cursor could not be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Service exposes a user's claim history and balances.
+type Service struct {
+	store *db.Store
+}
+
+// ClaimPage is one page of a user's claim history.
+type ClaimPage struct {
+	Claims     []db.ClaimLog
+	NextCursor string
+}
+
+// NewService wires dependencies.
+func NewService(store *db.Store) *Service {
+	return &Service{store: store}
+}
+
+// ListClaims returns the user's claims newest first. An empty cursor starts from the latest claim.
+func (s *Service) ListClaims(ctx context.Context, userID, cursor string, limit int) (*ClaimPage, error) {
+	if userID == "" {
+		return nil, errors.New("user id required")
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	var beforeID int64
+	if cursor != "" {
+		id, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, ErrInvalidCursor
+		}
+		beforeID = id
+	}
+	// Fetch one extra row to learn whether another page exists.
+	claims, err := s.store.ListClaimLogsByUser(ctx, userID, beforeID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &ClaimPage{Claims: claims}
+	if len(claims) > limit {
+		page.Claims = claims[:limit]
+		page.NextCursor = strconv.FormatInt(page.Claims[limit-1].ID, 10)
+	}
+	return page, nil
+}
+
+// Balances returns the user's wallet balance per currency.
+func (s *Service) Balances(ctx context.Context, userID string) ([]db.UserWallet, error) {
+	if userID == "" {
+		return nil, errors.New("user id required")
+	}
+	return s.store.ListUserWallets(ctx, userID)
+}
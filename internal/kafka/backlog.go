@@ -0,0 +1,105 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Backlog reads how far a consumer group has got through a topic, across all
+// partitions and group members.
+type Backlog struct {
+	admin   sarama.ClusterAdmin
+	client  sarama.Client
+	topic   string
+	groupID string
+}
+
+// NewBacklog connects to the brokers to follow groupID on topic.
+func NewBacklog(brokers []string, topic, groupID string) (*Backlog, error) {
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V3_5_0_0
+	client, err := sarama.NewClient(cleanBrokers(brokers), cfg)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return &Backlog{admin: admin, client: client, topic: topic, groupID: groupID}, nil
+}
+
+// Close closes the admin client and its connections.
+func (b *Backlog) Close() error {
+	return b.admin.Close()
+}
+
+// DrainedThrough reports whether the group has committed every message that
+// the topic received up to t, judged by the messages' Kafka timestamps.
+func (b *Backlog) DrainedThrough(ctx context.Context, t time.Time) (bool, error) {
+	type answer struct {
+		drained bool
+		err     error
+	}
+	result := make(chan answer, 1)
+	go func() {
+		drained, err := b.drainedThrough(t)
+		result <- answer{drained, err}
+	}()
+	select {
+	case a := <-result:
+		return a.drained, a.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (b *Backlog) drainedThrough(t time.Time) (bool, error) {
+	partitions, err := b.client.Partitions(b.topic)
+	if err != nil {
+		return false, err
+	}
+	committed, err := b.admin.ListConsumerGroupOffsets(b.groupID, map[string][]int32{b.topic: partitions})
+	if err != nil {
+		return false, err
+	}
+	if committed.Err != sarama.ErrNoError {
+		return false, committed.Err
+	}
+	for _, partition := range partitions {
+		// The first message after t, or the high-water mark if none has
+		// arrived yet.
+		target, err := b.client.GetOffset(b.topic, partition, t.UnixMilli()+1)
+		if err != nil {
+			return false, err
+		}
+		if target < 0 {
+			if target, err = b.client.GetOffset(b.topic, partition, sarama.OffsetNewest); err != nil {
+				return false, err
+			}
+		}
+		block := committed.GetBlock(b.topic, partition)
+		if block == nil {
+			return false, fmt.Errorf("no committed offset for %s/%d", b.topic, partition)
+		}
+		if block.Err != sarama.ErrNoError {
+			return false, block.Err
+		}
+		offset := block.Offset
+		if offset < 0 {
+			// Nothing committed yet, which only counts as drained for a
+			// partition that never held a message.
+			if offset, err = b.client.GetOffset(b.topic, partition, sarama.OffsetOldest); err != nil {
+				return false, err
+			}
+		}
+		if offset < target {
+			return false, nil
+		}
+	}
+	return true, nil
+}
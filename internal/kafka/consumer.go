@@ -2,12 +2,18 @@ package kafka
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"redpacket/internal/observability/logging"
 	"redpacket/internal/observability/metrics"
+	"redpacket/internal/observability/tracing"
 )
 
 // MessageHandler reacts to raw Kafka payloads.
@@ -25,27 +31,58 @@ func (f HandlerFunc) HandleMessage(ctx context.Context, value []byte) error {
 
 // Consumer consumes messages from Kafka and delegates to a handler.
 type Consumer struct {
+	client  sarama.Client
 	group   sarama.ConsumerGroup
 	topic   string
 	handler MessageHandler
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	memberID string
+	claims   map[int32]*claimProgress
+}
+
+// claimProgress tracks the next offset to process in a claimed partition.
+type claimProgress struct {
+	claim sarama.ConsumerGroupClaim
+	next  atomic.Int64
+}
+
+// GroupStatus describes the consumer's place in its group. Member is false
+// between sessions, such as during a rebalance. Lag counts the messages
+// waiting in the claimed partitions.
+type GroupStatus struct {
+	Member     bool
+	MemberID   string
+	Partitions int
+	Lag        int64
 }
 
 // NewConsumer creates a consumer group for the given topic.
-func NewConsumer(brokers []string, groupID, topic string, handler MessageHandler) (*Consumer, error) {
+func NewConsumer(brokers []string, groupID, topic string, handler MessageHandler, logger *slog.Logger) (*Consumer, error) {
 	cfg := sarama.NewConfig()
 	cfg.Version = sarama.V3_5_0_0
 	cfg.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRange
 	cfg.Consumer.Offsets.Initial = sarama.OffsetNewest
-	group, err := sarama.NewConsumerGroup(cleanBrokers(brokers), groupID, cfg)
+	client, err := sarama.NewClient(cleanBrokers(brokers), cfg)
 	if err != nil {
 		return nil, err
 	}
-	return &Consumer{group: group, topic: topic, handler: handler}, nil
+	group, err := sarama.NewConsumerGroupFromClient(groupID, client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return &Consumer{client: client, group: group, topic: topic, handler: handler, logger: logger, claims: make(map[int32]*claimProgress)}, nil
 }
 
+// lagReportInterval is how often the per-partition lag gauges are refreshed.
+const lagReportInterval = 5 * time.Second
+
 // Start begins consuming until the context is canceled.
 func (c *Consumer) Start(ctx context.Context) error {
-	handler := &consumerGroupHandler{handler: c.handler, ctx: ctx}
+	handler := &consumerGroupHandler{consumer: c, ctx: ctx}
+	go c.reportLag(ctx)
 	for {
 		if err := c.group.Consume(ctx, []string{c.topic}, handler); err != nil {
 			return err
@@ -58,29 +95,127 @@ func (c *Consumer) Start(ctx context.Context) error {
 
 // Close closes the consumer group.
 func (c *Consumer) Close() error {
-	return c.group.Close()
+	err := c.group.Close()
+	if closeErr := c.client.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Ping refreshes the topic's metadata, which needs a reachable broker.
+func (c *Consumer) Ping(ctx context.Context) error {
+	return refreshMetadata(ctx, c.client, c.topic)
+}
+
+// GroupStatus reports group membership and lag.
+func (c *Consumer) GroupStatus() GroupStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := GroupStatus{Member: c.memberID != "", MemberID: c.memberID, Partitions: len(c.claims)}
+	for _, p := range c.claims {
+		status.Lag += p.lag()
+	}
+	return status
+}
+
+// reportLag keeps the lag gauge of each claimed partition current, and drops
+// the gauges of partitions lost in a rebalance, until ctx is canceled.
+func (c *Consumer) reportLag(ctx context.Context) {
+	ticker := time.NewTicker(lagReportInterval)
+	defer ticker.Stop()
+	reported := make(map[int32]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		lags := make(map[int32]int64)
+		c.mu.Lock()
+		for partition, p := range c.claims {
+			lags[partition] = p.lag()
+		}
+		c.mu.Unlock()
+		for partition := range reported {
+			if _, ok := lags[partition]; !ok {
+				metrics.DeleteConsumerLag(c.topic, partition)
+				delete(reported, partition)
+			}
+		}
+		for partition, lag := range lags {
+			metrics.SetConsumerLag(c.topic, partition, lag)
+			reported[partition] = true
+		}
+	}
+}
+
+// lag counts the messages after the next offset to process.
+func (p *claimProgress) lag() int64 {
+	next := p.next.Load()
+	// Before the first message the offset may still be the OffsetNewest or
+	// OffsetOldest sentinel.
+	if next < 0 {
+		return 0
+	}
+	return max(p.claim.HighWaterMarkOffset()-next, 0)
 }
 
 type consumerGroupHandler struct {
-	handler MessageHandler
-	ctx     context.Context
+	consumer *Consumer
+	ctx      context.Context
+}
+
+func (h *consumerGroupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	h.consumer.mu.Lock()
+	h.consumer.memberID = session.MemberID()
+	h.consumer.mu.Unlock()
+	return nil
 }
 
-func (consumerGroupHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
-func (consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+func (h *consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	h.consumer.mu.Lock()
+	h.consumer.memberID = ""
+	h.consumer.mu.Unlock()
+	return nil
+}
 
 func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	ctx := h.ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	progress := &claimProgress{claim: claim}
+	progress.next.Store(claim.InitialOffset())
+	h.consumer.mu.Lock()
+	h.consumer.claims[claim.Partition()] = progress
+	h.consumer.mu.Unlock()
+	defer func() {
+		h.consumer.mu.Lock()
+		if h.consumer.claims[claim.Partition()] == progress {
+			delete(h.consumer.claims, claim.Partition())
+		}
+		h.consumer.mu.Unlock()
+	}()
+
 	for msg := range claim.Messages() {
 		start := time.Now()
-		if err := h.handler.HandleMessage(ctx, msg.Value); err != nil {
-			log.Printf("handler error: %v", err)
+		// The span continues the trace of the producer, when it sent one.
+		msgCtx, span := tracing.Start(extractTraceContext(ctx, msg), "kafka.consume", trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "kafka"),
+				attribute.String("messaging.destination.name", msg.Topic),
+				attribute.Int("messaging.kafka.partition", int(msg.Partition)),
+				attribute.Int64("messaging.kafka.offset", msg.Offset),
+			))
+		msgCtx = logging.With(msgCtx, slog.Int("partition", int(msg.Partition)), slog.Int64("offset", msg.Offset))
+		err := h.consumer.handler.HandleMessage(msgCtx, msg.Value)
+		if err != nil {
+			h.consumer.logger.ErrorContext(msgCtx, "message handler failed", logging.Err(err))
 		}
+		tracing.End(span, err)
 		metrics.ObserveKafkaOperation("consumer_message", time.Since(start))
 		session.MarkMessage(msg, "")
+		progress.next.Store(msg.Offset + 1)
 	}
 	return nil
 }
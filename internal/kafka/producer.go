@@ -2,18 +2,25 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"time"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"redpacket/internal/observability/metrics"
+	"redpacket/internal/observability/tracing"
+	"redpacket/internal/resilience"
 )
 
 // Producer wraps a synchronous Kafka producer.
 type Producer struct {
+	kafka  sarama.Client
 	client sarama.SyncProducer
 	topic  string
+	guard  *resilience.Guard
 }
 
 // NewProducer creates and connects a producer.
@@ -22,25 +29,128 @@ func NewProducer(brokers []string, topic string) (*Producer, error) {
 	cfg.Version = sarama.V3_5_0_0
 	cfg.Producer.Return.Successes = true
 	cfg.Producer.RequiredAcks = sarama.WaitForAll
-	producer, err := sarama.NewSyncProducer(cleanBrokers(brokers), cfg)
+	client, err := sarama.NewClient(cleanBrokers(brokers), cfg)
 	if err != nil {
 		return nil, err
 	}
-	return &Producer{client: producer, topic: topic}, nil
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return &Producer{kafka: client, client: producer, topic: topic}, nil
+}
+
+// SetGuard puts sends behind g. It must be called before the producer is used.
+func (p *Producer) SetGuard(g *resilience.Guard) {
+	p.guard = g
 }
 
 // Close shuts down the producer.
 func (p *Producer) Close() error {
-	return p.client.Close()
+	err := p.client.Close()
+	if closeErr := p.kafka.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Ping refreshes the topic's metadata, which needs a reachable broker.
+func (p *Producer) Ping(ctx context.Context) error {
+	return refreshMetadata(ctx, p.kafka, p.topic)
+}
+
+// Send publishes a byte payload to the configured topic. With a guard set,
+// Send returns once ctx or the guard's deadline is done, although the message
+// may still be delivered afterwards. The abandoned send keeps its slot until
+// sarama gives up, so stuck sends never exceed the guard's limit. The trace
+// context of ctx travels in the message headers.
+func (p *Producer) Send(ctx context.Context, payload []byte) error {
+	r, err := p.Reserve(ctx)
+	if err != nil {
+		return err
+	}
+	return r.Send(payload)
+}
+
+// Reservation holds a slot of the producer's guard for one message, so a
+// caller learns that Kafka is unavailable before doing work whose outcome must
+// be published.
+type Reservation struct {
+	p    *Producer
+	ctx  context.Context
+	done func(failed bool)
+	sent bool
 }
 
-// Send publishes a byte payload to the configured topic.
-func (p *Producer) Send(_ context.Context, payload []byte) error {
+// Reserve takes a guard slot for one message, or returns the guard's
+// *resilience.UnavailableError. The guard's deadline runs from the
+// reservation. The caller must then call Send or Release.
+func (p *Producer) Reserve(ctx context.Context) (*Reservation, error) {
+	ctx, done, err := p.guard.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Reservation{p: p, ctx: ctx, done: done}, nil
+}
+
+// Release gives the slot back unless Send was called, so it can be deferred.
+func (r *Reservation) Release() {
+	if !r.sent {
+		r.done(false)
+	}
+}
+
+// Send publishes payload in the reserved slot, as Producer.Send does with the
+// context passed to Reserve. A reservation sends at most once.
+func (r *Reservation) Send(payload []byte) (err error) {
+	if r.sent {
+		return errors.New("kafka reservation already used")
+	}
+	r.sent = true
+	p := r.p
 	start := time.Now()
-	defer metrics.ObserveKafkaOperation("producer_send", time.Since(start))
+	defer func() { metrics.ObserveKafkaOperation("producer_send", time.Since(start)) }()
+	ctx, span := tracing.Start(r.ctx, "kafka.send", trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.system", "kafka"), attribute.String("messaging.destination.name", p.topic)))
+	defer func() { tracing.End(span, err) }()
 	msg := &sarama.ProducerMessage{Topic: p.topic, Value: sarama.ByteEncoder(payload)}
-	_, _, err := p.client.SendMessage(msg)
-	return err
+	injectTraceContext(ctx, msg)
+	if p.guard == nil {
+		_, _, err := p.client.SendMessage(msg)
+		return err
+	}
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := p.client.SendMessage(msg)
+		result <- err
+		r.done(err != nil)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		// done cancels ctx only after the result is in.
+		select {
+		case err := <-result:
+			return err
+		default:
+			return ctx.Err()
+		}
+	}
+}
+
+// refreshMetadata gives up when ctx is done, leaving sarama's own timeouts to
+// end the refresh.
+func refreshMetadata(ctx context.Context, client sarama.Client, topic string) error {
+	result := make(chan error, 1)
+	go func() { result <- client.RefreshMetadata(topic) }()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func cleanBrokers(brokers []string) []string {
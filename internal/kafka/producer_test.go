@@ -0,0 +1,80 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama/mocks"
+
+	"redpacket/internal/resilience"
+)
+
+func newTestProducer(t *testing.T, guard *resilience.Guard) (*Producer, *mocks.SyncProducer) {
+	t.Helper()
+	mock := mocks.NewSyncProducer(t, nil)
+	t.Cleanup(func() { _ = mock.Close() })
+	return &Producer{client: mock, topic: "claims", guard: guard}, mock
+}
+
+func TestReservationHoldsSlotUntilSendOrRelease(t *testing.T) {
+	guard := resilience.NewGuard(resilience.Config{Name: "kafka_test", Timeout: time.Second, MaxInFlight: 1, FailureThreshold: 1, Cooldown: time.Minute})
+	p, mock := newTestProducer(t, guard)
+
+	r, err := p.Reserve(context.Background())
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := p.Reserve(context.Background()); !errors.Is(err, resilience.ErrUnavailable) {
+		t.Fatalf("second Reserve = %v, want ErrUnavailable", err)
+	}
+	r.Release()
+	r.Release()
+	if s := guard.Snapshot(); s.InFlight != 0 {
+		t.Fatalf("in flight = %d after Release, want 0", s.InFlight)
+	}
+
+	mock.ExpectSendMessageAndSucceed()
+	r, err = p.Reserve(context.Background())
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if err := r.Send([]byte("{}")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := r.Send([]byte("{}")); err == nil {
+		t.Fatal("second Send succeeded")
+	}
+	// Release after Send must not free the slot a second time.
+	r.Release()
+	if s := guard.Snapshot(); s.InFlight != 0 {
+		t.Fatalf("in flight = %d after Send, want 0", s.InFlight)
+	}
+}
+
+func TestReserveFailsWhileBreakerIsOpen(t *testing.T) {
+	guard := resilience.NewGuard(resilience.Config{Name: "kafka_test", Timeout: time.Second, FailureThreshold: 1, Cooldown: time.Minute})
+	p, mock := newTestProducer(t, guard)
+
+	mock.ExpectSendMessageAndFail(errors.New("broker down"))
+	if err := p.Send(context.Background(), []byte("{}")); err == nil {
+		t.Fatal("Send succeeded, want the broker error")
+	}
+	if _, err := p.Reserve(context.Background()); !errors.Is(err, resilience.ErrUnavailable) {
+		t.Fatalf("Reserve = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestReserveWithoutGuard(t *testing.T) {
+	p, mock := newTestProducer(t, nil)
+	mock.ExpectSendMessageAndSucceed()
+	r, err := p.Reserve(context.Background())
+	if err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	defer r.Release()
+	if err := r.Send([]byte("{}")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+}
@@ -3,10 +3,11 @@ package claim
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 
 	"redpacket/internal/domain/campaign"
 	"redpacket/internal/kafka"
+	"redpacket/internal/observability/logging"
 )
 
 // Handler reacts to decoded claim events.
@@ -27,17 +28,20 @@ type Consumer struct {
 	consumer *kafka.Consumer
 }
 
-// NewConsumer wires the handler through the low-level consumer.
-func NewConsumer(brokers []string, groupID, topic string, handler Handler) (*Consumer, error) {
+// NewConsumer wires the handler through the low-level consumer. Events are
+// handled with a context whose log lines carry their campaign, user and
+// claim IDs.
+func NewConsumer(brokers []string, groupID, topic string, handler Handler, logger *slog.Logger) (*Consumer, error) {
 	llHandler := kafka.HandlerFunc(func(ctx context.Context, value []byte) error {
 		var event campaign.ClaimEvent
 		if err := json.Unmarshal(value, &event); err != nil {
-			log.Printf("claim consumer decode error: %v", err)
+			logger.ErrorContext(ctx, "failed to decode claim event", logging.Err(err))
 			return nil
 		}
+		ctx = logging.With(ctx, logging.CampaignID(event.CampaignID), logging.UserID(event.UserID), logging.ClaimID(event.ClaimID))
 		return handler.HandleClaim(ctx, event)
 	})
-	cons, err := kafka.NewConsumer(brokers, groupID, topic, llHandler)
+	cons, err := kafka.NewConsumer(brokers, groupID, topic, llHandler, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -49,6 +53,16 @@ func (c *Consumer) Start(ctx context.Context) error {
 	return c.consumer.Start(ctx)
 }
 
+// Ping checks that a Kafka broker is reachable.
+func (c *Consumer) Ping(ctx context.Context) error {
+	return c.consumer.Ping(ctx)
+}
+
+// GroupStatus reports group membership and lag.
+func (c *Consumer) GroupStatus() kafka.GroupStatus {
+	return c.consumer.GroupStatus()
+}
+
 // Close cleans up resources.
 func (c *Consumer) Close() error {
 	return c.consumer.Close()
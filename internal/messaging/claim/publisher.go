@@ -26,3 +26,34 @@ func (p *Publisher) Publish(ctx context.Context, event campaign.ClaimEvent) erro
 	}
 	return p.producer.Send(ctx, payload)
 }
+
+// Reservation is a Kafka slot held for one claim event.
+type Reservation struct {
+	r *kafka.Reservation
+}
+
+// Reserve takes a Kafka slot for one event before the claim is made, so no
+// packet is handed out while its event cannot be published. The caller must
+// then call Publish or Release.
+func (p *Publisher) Reserve(ctx context.Context) (*Reservation, error) {
+	r, err := p.producer.Reserve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Reservation{r: r}, nil
+}
+
+// Publish pushes a claim event onto Kafka in the reserved slot.
+func (r *Reservation) Publish(event campaign.ClaimEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return r.r.Send(payload)
+}
+
+// Release gives the slot back unless the event was published, so it can be
+// deferred.
+func (r *Reservation) Release() {
+	r.r.Release()
+}
@@ -0,0 +1,28 @@
+package settlement
+
+import (
+	"context"
+	"encoding/json"
+
+	"redpacket/internal/domain/settlement"
+	"redpacket/internal/kafka"
+)
+
+// Publisher converts settlement events into Kafka messages.
+type Publisher struct {
+	producer *kafka.Producer
+}
+
+// NewPublisher constructs a Publisher.
+func NewPublisher(producer *kafka.Producer) *Publisher {
+	return &Publisher{producer: producer}
+}
+
+// Publish pushes a settlement event onto Kafka.
+func (p *Publisher) Publish(ctx context.Context, event settlement.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.producer.Send(ctx, payload)
+}
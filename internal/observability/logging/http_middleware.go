@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from callers.
+const maxRequestIDLength = 128
+
+// GinMiddleware propagates the caller's X-Request-ID, or generates one, echoes
+// it on the response and adds it to the request context, then logs each
+// request once it is served, with the error a handler attached through
+// gin.Context.Error, if any. Requests for which quiet reports true, such as
+// probes, are logged at debug level.
+func GinMiddleware(logger *slog.Logger, quiet func(*http.Request) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = NewID()
+		}
+		c.Header(RequestIDHeader, id)
+		ctx := With(c.Request.Context(), RequestID(id))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		// Handlers may add IDs, such as the campaign, to the request context.
+		ctx = c.Request.Context()
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case quiet != nil && quiet(c.Request):
+			level = slog.LevelDebug
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if err := c.Errors.Last(); err != nil {
+			attrs = append(attrs, slog.String("error", err.Err.Error()))
+		}
+		logger.LogAttrs(ctx, level, "http request", attrs...)
+	}
+}
+
+// NewID returns a random 16-byte hex identifier.
+func NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID accepts printable ASCII IDs of bounded length, so callers
+// cannot inject arbitrary content into logs and response headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
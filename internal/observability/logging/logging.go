@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys shared by every component, so lines about the same request,
+// campaign or claim can be joined across services.
+const (
+	KeyRequestID  = "request_id"
+	KeyCampaignID = "campaign_id"
+	KeyUserID     = "user_id"
+	KeyClaimID    = "claim_id"
+)
+
+// Config tunes a logger. Level is debug, info, warn or error. Sampling keeps
+// the first SampleFirst lines with the same level and message in each second
+// and then every SampleThereafter-th; a zero SampleFirst disables it.
+type Config struct {
+	Level            string
+	SampleFirst      int
+	SampleThereafter int
+}
+
+// New builds a logger writing JSON lines to w. Lines logged with a context
+// carry the attributes added by With and the trace and span IDs of the
+// context's span.
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	var h slog.Handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	if cfg.SampleFirst > 0 {
+		h = newSamplingHandler(h, cfg.SampleFirst, cfg.SampleThereafter, time.Second)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+// ParseLevel parses a level name; an empty name is info.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+type ctxKey struct{}
+
+// With returns a context whose log lines carry attrs in addition to the ones
+// already added to ctx.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev := Attrs(ctx)
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	merged = append(merged, prev...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxKey{}, merged)
+}
+
+// Attrs returns the attributes added to ctx by With.
+func Attrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+// RequestID is the request_id attribute.
+func RequestID(id string) slog.Attr { return slog.String(KeyRequestID, id) }
+
+// CampaignID is the campaign_id attribute.
+func CampaignID(id int64) slog.Attr { return slog.Int64(KeyCampaignID, id) }
+
+// UserID is the user_id attribute.
+func UserID(id string) slog.Attr { return slog.String(KeyUserID, id) }
+
+// ClaimID is the claim_id attribute.
+func ClaimID(id string) slog.Attr { return slog.String(KeyClaimID, id) }
+
+// Err is the error attribute.
+func Err(err error) slog.Attr { return slog.Any("error", err) }
+
+// contextHandler adds the attributes carried by the context to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		r.AddAttrs(Attrs(ctx)...)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
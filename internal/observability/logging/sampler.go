@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// samplingHandler drops repeats of hot lines: per tick, it passes the first
+// `first` records with a given level and message and then every
+// `thereafter`-th, or none when thereafter is zero. Messages are constant
+// strings, so the set of counters stays small.
+type samplingHandler struct {
+	slog.Handler
+	s *sampler
+}
+
+type sampler struct {
+	first      uint64
+	thereafter uint64
+	tick       time.Duration
+	counters   sync.Map // samplerKey -> *sampleCounter
+}
+
+type samplerKey struct {
+	level   slog.Level
+	message string
+}
+
+type sampleCounter struct {
+	resetAt atomic.Int64
+	n       atomic.Uint64
+}
+
+func newSamplingHandler(h slog.Handler, first, thereafter int, tick time.Duration) samplingHandler {
+	return samplingHandler{Handler: h, s: &sampler{first: uint64(first), thereafter: uint64(max(thereafter, 0)), tick: tick}}
+}
+
+func (h samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.s.allow(r.Level, r.Message, r.Time) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithAttrs(attrs), s: h.s}
+}
+
+func (h samplingHandler) WithGroup(name string) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithGroup(name), s: h.s}
+}
+
+func (s *sampler) allow(level slog.Level, message string, now time.Time) bool {
+	key := samplerKey{level: level, message: message}
+	v, ok := s.counters.Load(key)
+	if !ok {
+		v, _ = s.counters.LoadOrStore(key, &sampleCounter{})
+	}
+	n := v.(*sampleCounter).inc(now, s.tick)
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+// inc counts a record and returns its position within the current tick.
+func (c *sampleCounter) inc(now time.Time, tick time.Duration) uint64 {
+	t := now.UnixNano()
+	resetAt := c.resetAt.Load()
+	if t > resetAt && c.resetAt.CompareAndSwap(resetAt, t+int64(tick)) {
+		c.n.Store(1)
+		return 1
+	}
+	return c.n.Add(1)
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -38,8 +39,116 @@ var (
 		Help:    "Time spent processing claim events in the consumer service",
 		Buckets: prometheus.DefBuckets,
 	}, []string{"step"})
+
+	campaignsStartedUnwarmed = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "campaigns_started_unwarmed",
+		Help: "Campaigns inside their window whose Redis state was never primed",
+	})
+
+	campaignsWarmedLate = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "campaigns_warmed_late_total",
+		Help: "Campaigns primed in Redis only after their start time",
+	})
+
+	campaignsReprimed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "campaigns_reprimed_total",
+		Help: "Warmed campaigns primed again because their Redis window key was missing",
+	})
+
+	claimShardFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "claim_shard_fallbacks_total",
+		Help: "Claims that found their home inventory shard sold out, by outcome of the neighbour search",
+	}, []string{"outcome"})
+
+	claimGateDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "claim_gate_decisions_total",
+		Help: "Opens checked against the campaign state cache, by decision: script, or answered in process as sold_out, inactive or not_found",
+	}, []string{"decision"})
+
+	claimBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "claim_batch_size",
+		Help:    "Claims sent to Redis in one batched script call",
+		Buckets: []float64{1, 2, 4, 8, 16, 32, 64, 128, 256},
+	})
+
+	claimBatchWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "claim_batch_wait_seconds",
+		Help:    "Time from a claim batch opening to its script call",
+		Buckets: []float64{.00005, .0001, .00025, .0005, .001, .0025, .005, .01},
+	})
+
+	claimBatchAbandoned = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "claim_batch_abandoned_total",
+		Help: "Batched claims whose caller stopped waiting, by outcome: dropped before the batch was sent, released after it ran, or release_failed",
+	}, []string{"outcome"})
+
+	waitingRoomDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "waiting_room_depth",
+		Help: "Waiting room tickets issued but not yet admitted, for tracked campaigns",
+	}, []string{"campaign_id"})
+
+	waitingRoomAdmitRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "waiting_room_admit_rate",
+		Help: "Waiting room tickets admitted per second, for tracked campaigns",
+	}, []string{"campaign_id"})
+
+	waitingRoomTickets = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "waiting_room_joins_total",
+		Help: "Waiting room joins, including users fetching their existing ticket",
+	})
+
+	waitingRoomChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "waiting_room_checks_total",
+		Help: "Opens checked against the waiting room, by result: admitted, waiting or invalid",
+	}, []string{"result"})
+
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "dependency_breaker_state",
+		Help: "Circuit breaker state per dependency: 0 closed, 1 half open, 2 open",
+	}, []string{"dependency"})
+
+	dependencyInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "dependency_in_flight",
+		Help: "Calls in flight per dependency",
+	}, []string{"dependency"})
+
+	dependencyRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dependency_rejections_total",
+		Help: "Calls rejected without reaching the dependency, by reason: open breaker or saturated concurrency limit",
+	}, []string{"dependency", "reason"})
+
+	claimOpens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "claim_opens_total",
+		Help: "Red packet opens by campaign and outcome: ok, already_opened, sold_out, inactive, not_found, not_admitted, invalid_token, unavailable or error",
+	}, []string{"campaign_id", "status"})
+
+	claimedAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "claimed_amount_total",
+		Help: "Amount handed out by successful opens, in minor units of currency",
+	}, []string{"campaign_id", "currency"})
+
+	campaignPacketsRemaining = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "campaign_packets_remaining",
+		Help: "Packets left in Redis per live campaign, as of the last refresh",
+	}, []string{"campaign_id"})
+
+	kafkaConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Messages waiting in each partition claimed by the consumer",
+	}, []string{"topic", "partition"})
 )
 
+// OtherCampaign is the campaign_id label of campaigns that are not tracked.
+const OtherCampaign = "other"
+
+// trackedCampaigns holds the campaigns labelled by their own id. Every other
+// campaign is counted under OtherCampaign, so clients cannot grow the label
+// set by opening arbitrary ids.
+var trackedCampaigns = struct {
+	sync.RWMutex
+	labels map[int64]string
+}{labels: make(map[int64]string)}
+
 // ObserveHTTPRequest tracks the handling time of HTTP requests.
 func ObserveHTTPRequest(method, route string, status int, d time.Duration) {
 	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(d.Seconds())
@@ -64,3 +173,136 @@ func ObserveKafkaOperation(operation string, d time.Duration) {
 func ObserveConsumerProcessing(step string, d time.Duration) {
 	consumerProcessDuration.WithLabelValues(step).Observe(d.Seconds())
 }
+
+// SetCampaignsStartedUnwarmed records how many started campaigns are still unwarmed.
+func SetCampaignsStartedUnwarmed(n int64) {
+	campaignsStartedUnwarmed.Set(float64(n))
+}
+
+// IncCampaignsWarmedLate counts a campaign primed after its start time.
+func IncCampaignsWarmedLate() {
+	campaignsWarmedLate.Inc()
+}
+
+// IncCampaignsReprimed counts a warmed campaign primed again.
+func IncCampaignsReprimed() {
+	campaignsReprimed.Inc()
+}
+
+// IncClaimShardFallback counts a claim that fell back to neighbour shards.
+func IncClaimShardFallback(outcome string) {
+	claimShardFallbacks.WithLabelValues(outcome).Inc()
+}
+
+// IncClaimGateDecision counts an open checked against the campaign state cache.
+func IncClaimGateDecision(decision string) {
+	claimGateDecisions.WithLabelValues(decision).Inc()
+}
+
+// ObserveClaimBatch records the size of a batched claim call and how long its
+// first claim waited for it.
+func ObserveClaimBatch(size int, wait time.Duration) {
+	claimBatchSize.Observe(float64(size))
+	claimBatchWait.Observe(wait.Seconds())
+}
+
+// IncClaimBatchAbandoned counts a batched claim whose caller stopped waiting.
+func IncClaimBatchAbandoned(outcome string) {
+	claimBatchAbandoned.WithLabelValues(outcome).Inc()
+}
+
+// IncWaitingRoomJoin counts a waiting room join.
+func IncWaitingRoomJoin() {
+	waitingRoomTickets.Inc()
+}
+
+// SetWaitingRoom records a tracked campaign's waiting room depth and admit
+// rate. Untracked campaigns are ignored.
+func SetWaitingRoom(campaignID int64, depth int64, rate int) {
+	if label, ok := campaignLabel(campaignID); ok {
+		waitingRoomDepth.WithLabelValues(label).Set(float64(depth))
+		waitingRoomAdmitRate.WithLabelValues(label).Set(float64(rate))
+	}
+}
+
+// IncWaitingRoomCheck counts an open checked against the waiting room.
+func IncWaitingRoomCheck(result string) {
+	waitingRoomChecks.WithLabelValues(result).Inc()
+}
+
+// SetBreakerState records a dependency's circuit breaker state.
+func SetBreakerState(dependency string, state int) {
+	breakerState.WithLabelValues(dependency).Set(float64(state))
+}
+
+// SetDependencyInFlight records the calls in flight to a dependency.
+func SetDependencyInFlight(dependency string, n int) {
+	dependencyInFlight.WithLabelValues(dependency).Set(float64(n))
+}
+
+// IncDependencyRejection counts a call turned away before reaching a dependency.
+func IncDependencyRejection(dependency, reason string) {
+	dependencyRejections.WithLabelValues(dependency, reason).Inc()
+}
+
+// SetTrackedCampaigns replaces the campaigns labelled by their own id and
+// deletes the series of campaigns that are no longer tracked.
+func SetTrackedCampaigns(ids []int64) {
+	labels := make(map[int64]string, len(ids))
+	for _, id := range ids {
+		labels[id] = strconv.FormatInt(id, 10)
+	}
+	trackedCampaigns.Lock()
+	prev := trackedCampaigns.labels
+	trackedCampaigns.labels = labels
+	trackedCampaigns.Unlock()
+	for id, label := range prev {
+		if _, ok := labels[id]; !ok {
+			match := prometheus.Labels{"campaign_id": label}
+			claimOpens.DeletePartialMatch(match)
+			claimedAmount.DeletePartialMatch(match)
+			campaignPacketsRemaining.DeletePartialMatch(match)
+			waitingRoomDepth.DeletePartialMatch(match)
+			waitingRoomAdmitRate.DeletePartialMatch(match)
+		}
+	}
+}
+
+func campaignLabel(campaignID int64) (string, bool) {
+	trackedCampaigns.RLock()
+	defer trackedCampaigns.RUnlock()
+	if label, ok := trackedCampaigns.labels[campaignID]; ok {
+		return label, true
+	}
+	return OtherCampaign, false
+}
+
+// IncClaimOpen counts an open of a campaign by outcome.
+func IncClaimOpen(campaignID int64, status string) {
+	label, _ := campaignLabel(campaignID)
+	claimOpens.WithLabelValues(label, status).Inc()
+}
+
+// AddClaimedAmount counts the amount handed out by a successful open.
+func AddClaimedAmount(campaignID int64, currency string, amount int64) {
+	label, _ := campaignLabel(campaignID)
+	claimedAmount.WithLabelValues(label, currency).Add(float64(amount))
+}
+
+// SetCampaignPacketsRemaining records a tracked campaign's packets left.
+// Untracked campaigns are ignored.
+func SetCampaignPacketsRemaining(campaignID int64, n int64) {
+	if label, ok := campaignLabel(campaignID); ok {
+		campaignPacketsRemaining.WithLabelValues(label).Set(float64(n))
+	}
+}
+
+// SetConsumerLag records the lag of a claimed partition.
+func SetConsumerLag(topic string, partition int32, lag int64) {
+	kafkaConsumerLag.WithLabelValues(topic, strconv.Itoa(int(partition))).Set(float64(lag))
+}
+
+// DeleteConsumerLag drops the lag of a partition that is no longer claimed.
+func DeleteConsumerLag(topic string, partition int32) {
+	kafkaConsumerLag.DeleteLabelValues(topic, strconv.Itoa(int(partition)))
+}
@@ -0,0 +1,75 @@
+package paymentprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"redpacket/internal/domain/payout"
+)
+
+// Client is a payout.Provider that talks to a wallet service over HTTP.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient builds a Client for the provider at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), http: &http.Client{}}
+}
+
+type payoutRequest struct {
+	CampaignID int64  `json:"campaign_id"`
+	UserID     string `json:"user_id"`
+	Amount     int64  `json:"amount"`
+	Currency   string `json:"currency"`
+}
+
+type payoutResponse struct {
+	Reference string `json:"reference"`
+	Error     string `json:"error"`
+}
+
+// Pay implements payout.Provider. 4xx responses other than 408 and 429 are permanent failures.
+func (c *Client) Pay(ctx context.Context, req payout.Request) (payout.Result, error) {
+	body, err := json.Marshal(payoutRequest{
+		CampaignID: req.CampaignID,
+		UserID:     req.UserID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+	})
+	if err != nil {
+		return payout.Result{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/payouts", bytes.NewReader(body))
+	if err != nil {
+		return payout.Result{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Idempotency-Key", req.IdempotencyKey)
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return payout.Result{}, err
+	}
+	defer resp.Body.Close()
+
+	var out payoutResponse
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	_ = json.Unmarshal(raw, &out)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return payout.Result{Reference: out.Reference}, nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return payout.Result{}, fmt.Errorf("%w: provider returned %d: %s", payout.ErrPermanent, resp.StatusCode, out.Error)
+	default:
+		return payout.Result{}, fmt.Errorf("provider returned %d: %s", resp.StatusCode, out.Error)
+	}
+}
@@ -0,0 +1,72 @@
+package paymentprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MockServer is an in-memory wallet service used by compose and local runs.
+// It honours Idempotency-Key and can inject transient failures and latency.
+type MockServer struct {
+	FailureRate float64
+	Latency     time.Duration
+
+	mu       sync.Mutex
+	payouts  map[string]payoutResponse
+	sequence int64
+}
+
+// NewMockServer builds a MockServer.
+func NewMockServer(failureRate float64, latency time.Duration) *MockServer {
+	return &MockServer{FailureRate: failureRate, Latency: latency, payouts: make(map[string]payoutResponse)}
+}
+
+// ServeHTTP implements http.Handler.
+func (m *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/payouts" {
+		http.NotFound(w, r)
+		return
+	}
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		writeJSON(w, http.StatusBadRequest, payoutResponse{Error: "missing Idempotency-Key"})
+		return
+	}
+	var req payoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, payoutResponse{Error: err.Error()})
+		return
+	}
+	if req.Amount <= 0 || req.Currency == "" || req.UserID == "" {
+		writeJSON(w, http.StatusUnprocessableEntity, payoutResponse{Error: "invalid payout"})
+		return
+	}
+	if m.Latency > 0 {
+		time.Sleep(m.Latency)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.payouts[key]; ok {
+		writeJSON(w, http.StatusOK, existing)
+		return
+	}
+	if m.FailureRate > 0 && rand.Float64() < m.FailureRate {
+		writeJSON(w, http.StatusServiceUnavailable, payoutResponse{Error: "injected failure"})
+		return
+	}
+	m.sequence++
+	resp := payoutResponse{Reference: fmt.Sprintf("mock-%d", m.sequence)}
+	m.payouts[key] = resp
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,67 @@
+package paymentprovider
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"redpacket/internal/domain/payout"
+)
+
+func TestMockServerHonoursIdempotencyKey(t *testing.T) {
+	srv := httptest.NewServer(NewMockServer(0, 0))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+	req := payout.Request{IdempotencyKey: "payout:7:alice", CampaignID: 7, UserID: "alice", Amount: 100, Currency: "USD"}
+
+	first, err := client.Pay(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Pay: %v", err)
+	}
+	again, err := client.Pay(context.Background(), req)
+	if err != nil {
+		t.Fatalf("repeated Pay: %v", err)
+	}
+	if again.Reference != first.Reference {
+		t.Fatalf("repeated Pay reference = %q, want %q", again.Reference, first.Reference)
+	}
+
+	req.IdempotencyKey = "payout:7:bob"
+	other, err := client.Pay(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Pay with a new key: %v", err)
+	}
+	if other.Reference == first.Reference {
+		t.Fatalf("new key reused reference %q", other.Reference)
+	}
+}
+
+func TestClientClassifiesProviderErrors(t *testing.T) {
+	req := payout.Request{IdempotencyKey: "payout:7:alice", CampaignID: 7, UserID: "alice", Amount: 100, Currency: "USD"}
+	tests := []struct {
+		name      string
+		mock      *MockServer
+		mutate    func(*payout.Request)
+		permanent bool
+	}{
+		{name: "missing key", mock: NewMockServer(0, 0), mutate: func(r *payout.Request) { r.IdempotencyKey = "" }, permanent: true},
+		{name: "invalid payout", mock: NewMockServer(0, 0), mutate: func(r *payout.Request) { r.Amount = 0 }, permanent: true},
+		{name: "injected failure", mock: NewMockServer(1, 0), mutate: func(*payout.Request) {}, permanent: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.mock)
+			defer srv.Close()
+			r := req
+			tt.mutate(&r)
+			_, err := NewClient(srv.URL).Pay(context.Background(), r)
+			if err == nil {
+				t.Fatal("Pay succeeded, want an error")
+			}
+			if got := errors.Is(err, payout.ErrPermanent); got != tt.permanent {
+				t.Fatalf("permanent = %v (%v), want %v", got, err, tt.permanent)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"redpacket/internal/observability/metrics"
+	"redpacket/internal/observability/tracing"
+)
+
+// defaultBatchMaxSize applies when batching is enabled without a size.
+const defaultBatchMaxSize = 64
+
+// defaultBatchTimeout applies when batching is enabled without a timeout.
+const defaultBatchTimeout = time.Second
+
+// claimBatcher collects claims for the same campaign shard that arrive within
+// window of the first one and runs them in one script call. A batch is sent
+// early once it holds maxSize users, and runs on its own timeout.
+type claimBatcher struct {
+	client  *Client
+	window  time.Duration
+	maxSize int
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[batchKey]*claimBatch
+}
+
+type batchKey struct {
+	campaignID int64
+	shard      int
+}
+
+type claimBatch struct {
+	key     batchKey
+	layout  *campaignLayout
+	waiters []*claimWaiter
+	opened  time.Time
+	timer   *time.Timer
+	done    chan struct{}
+	err     error
+}
+
+// A claimWaiter is one caller in a batch. Whichever of the caller and the
+// batch first moves state away from waiterWaiting decides whether the caller
+// takes the reply or the batch releases the claim.
+type claimWaiter struct {
+	ctx    context.Context
+	userID string
+	state  atomic.Int32
+	reply  []interface{}
+}
+
+const (
+	waiterWaiting int32 = iota
+	waiterAnswered
+	waiterGone
+)
+
+func newClaimBatcher(client *Client, window time.Duration, maxSize int, timeout time.Duration) *claimBatcher {
+	if maxSize <= 0 {
+		maxSize = defaultBatchMaxSize
+	}
+	if timeout <= 0 {
+		timeout = defaultBatchTimeout
+	}
+	return &claimBatcher{client: client, window: window, maxSize: maxSize, timeout: timeout, pending: make(map[batchKey]*claimBatch)}
+}
+
+// claim adds the user to the shard's open batch and waits for its reply. A
+// caller whose ctx is done before the batch is sent is left out of it; one
+// that stops waiting while the batch runs has its packet or reservation
+// released.
+func (b *claimBatcher) claim(ctx context.Context, layout *campaignLayout, campaignID int64, shard int, userID string) ([]interface{}, error) {
+	key := batchKey{campaignID: campaignID, shard: shard}
+	w := &claimWaiter{ctx: ctx, userID: userID}
+	b.mu.Lock()
+	batch, ok := b.pending[key]
+	if !ok {
+		batch = &claimBatch{key: key, layout: layout, opened: time.Now(), done: make(chan struct{})}
+		batch.timer = time.AfterFunc(b.window, func() { b.flush(batch) })
+		b.pending[key] = batch
+	}
+	batch.waiters = append(batch.waiters, w)
+	full := len(batch.waiters) >= b.maxSize
+	if full {
+		delete(b.pending, key)
+	}
+	b.mu.Unlock()
+	if full {
+		batch.timer.Stop()
+		go b.run(batch)
+	}
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		if w.state.CompareAndSwap(waiterWaiting, waiterGone) {
+			return nil, ctx.Err()
+		}
+		// The batch answered first and is about to wake its callers.
+		<-batch.done
+	}
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	if w.reply == nil {
+		return nil, ctx.Err()
+	}
+	return w.reply, nil
+}
+
+// flush runs the batch when its window closes, unless it already filled up.
+func (b *claimBatcher) flush(batch *claimBatch) {
+	b.mu.Lock()
+	if b.pending[batch.key] != batch {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, batch.key)
+	b.mu.Unlock()
+	b.run(batch)
+}
+
+// run executes a detached batch for the callers still waiting and wakes them.
+// Claims made for callers that left meanwhile are released.
+func (b *claimBatcher) run(batch *claimBatch) {
+	defer close(batch.done)
+	now := time.Now()
+	live := make([]*claimWaiter, 0, len(batch.waiters))
+	users := make([]string, 0, len(batch.waiters))
+	links := make([]trace.Link, 0, len(batch.waiters))
+	for _, w := range batch.waiters {
+		if w.ctx.Err() != nil {
+			metrics.IncClaimBatchAbandoned("dropped")
+			continue
+		}
+		live = append(live, w)
+		users = append(users, w.userID)
+		if sc := trace.SpanContextFromContext(w.ctx); sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	if len(live) == 0 {
+		return
+	}
+	metrics.ObserveClaimBatch(len(users), now.Sub(batch.opened))
+	// The batch serves several callers, so it runs on its own deadline and
+	// in its own trace, linked to each caller's span.
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "redis.claim_batch", trace.WithSpanKind(trace.SpanKindClient), trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int64("campaign.id", batch.key.campaignID), attribute.Int("claim.shard", batch.key.shard), attribute.Int("claim.batch_size", len(users))))
+	defer func() { tracing.End(span, batch.err) }()
+	var replies [][]interface{}
+	replies, batch.err = b.client.claimHome(ctx, batch.layout, batch.key.campaignID, batch.key.shard, users, now)
+	if batch.err != nil {
+		return
+	}
+	for i, w := range live {
+		w.reply = replies[i]
+		if w.state.CompareAndSwap(waiterWaiting, waiterAnswered) {
+			continue
+		}
+		released, err := b.client.releaseClaim(ctx, batch.layout, batch.key.campaignID, batch.key.shard, w.userID, replies[i])
+		switch {
+		case err != nil:
+			metrics.IncClaimBatchAbandoned("release_failed")
+		case released:
+			metrics.IncClaimBatchAbandoned("released")
+		}
+	}
+}
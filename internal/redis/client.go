@@ -2,33 +2,112 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	goRedis "github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"redpacket/internal/observability/metrics"
+	"redpacket/internal/observability/tracing"
+	"redpacket/internal/resilience"
 	"redpacket/scripts/lua"
 )
 
+// LeaderboardEntry is one member of a campaign's winners sorted set. Rank is 1-based.
+type LeaderboardEntry struct {
+	Rank   int64
+	UserID string
+	Amount int64
+}
+
+// Options selects the Redis deployment. A single address is a standalone
+// node, several addresses a cluster, and a MasterName a Sentinel-managed
+// master with Addrs pointing at the sentinels. Cluster forces cluster mode for
+// a single seed address. A positive BatchWindow batches concurrent claims on
+// the same campaign shard into one script call of up to BatchMaxSize users,
+// which runs detached from its callers within BatchTimeout. A non-nil Guard applies its circuit breaker, concurrency limit and deadline
+// to every command.
+type Options struct {
+	Addrs        []string
+	MasterName   string
+	Password     string
+	Cluster      bool
+	BatchWindow  time.Duration
+	BatchMaxSize int
+	BatchTimeout time.Duration
+	Guard        *resilience.Guard
+}
+
 // Client wraps go-redis and exposes helpers for campaign keys and Lua execution.
 type Client struct {
-	rdb         *goRedis.Client
+	rdb         goRedis.UniversalClient
 	claimScript *goRedis.Script
+	claimShard  *goRedis.Script
+	claimQueue  *goRedis.Script
+	release     *goRedis.Script
+	queueTicket *goRedis.Script
+	lockAcquire *goRedis.Script
+	lockRelease *goRedis.Script
+
+	// batcher groups concurrent claims per home shard; nil when disabled.
+	batcher *claimBatcher
+
+	// layouts caches each campaign's tier amounts and layout, which never
+	// change once the campaign is primed, so claims need not read them
+	// before the script. See layoutCacheTTL and maxCachedLayouts.
+	layoutsMu sync.RWMutex
+	layouts   map[int64]cachedLayout
 }
 
 // New creates a Redis client and verifies connectivity.
-func New(addr string) (*Client, error) {
-	rdb := goRedis.NewClient(&goRedis.Options{Addr: addr})
+func New(opts Options) (*Client, error) {
+	universal := &goRedis.UniversalOptions{
+		Addrs:      opts.Addrs,
+		MasterName: opts.MasterName,
+		Password:   opts.Password,
+		// Lets the guard's deadlines bound socket reads and writes.
+		ContextTimeoutEnabled: true,
+	}
+	var rdb goRedis.UniversalClient
+	if opts.Cluster {
+		rdb = goRedis.NewClusterClient(universal.Cluster())
+	} else {
+		rdb = goRedis.NewUniversalClient(universal)
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
 		return nil, err
 	}
-	return &Client{
+	if opts.Guard != nil {
+		rdb.AddHook(guardHook{guard: opts.Guard})
+	}
+	c := &Client{
 		rdb:         rdb,
 		claimScript: goRedis.NewScript(lua.ClaimScript),
-	}, nil
+		claimShard:  goRedis.NewScript(lua.ClaimShardScript),
+		claimQueue:  goRedis.NewScript(lua.ClaimQueueScript),
+		release:     goRedis.NewScript(lua.ReleaseClaimScript),
+		queueTicket: goRedis.NewScript(lua.QueueTicketScript),
+		lockAcquire: goRedis.NewScript(lua.LockAcquireScript),
+		lockRelease: goRedis.NewScript(lua.LockReleaseScript),
+		layouts:     make(map[int64]cachedLayout),
+	}
+	if opts.BatchWindow > 0 {
+		c.batcher = newClaimBatcher(c, opts.BatchWindow, opts.BatchMaxSize, opts.BatchTimeout)
+	}
+	return c, nil
+}
+
+// Ping checks that Redis answers.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.rdb.Ping(ctx).Err()
 }
 
 // Close shuts down the underlying Redis client.
@@ -36,63 +115,576 @@ func (c *Client) Close() error {
 	return c.rdb.Close()
 }
 
-// RunClaimScript executes the Lua script atomically.
-func (c *Client) RunClaimScript(ctx context.Context, keys []string, args ...interface{}) ([]interface{}, error) {
+// RunClaimScript claims a packet for the user and returns {status, amount,
+// currency}. The claim runs on the user's home shard, batched with concurrent
+// claims there when batching is enabled. If the home shard is sold out, the
+// user stays reserved in its opened set while the other shards are tried in
+// turn, and the reservation is released if none has a packet. If a neighbour
+// fails, or the caller leaves before its packet is returned, whatever the
+// neighbour handed out goes back before the reservation is dropped.
+func (c *Client) RunClaimScript(ctx context.Context, campaignID int64, userID string, now time.Time) ([]interface{}, error) {
+	ctx, span := tracing.Start(ctx, "redis.claim", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int64("campaign.id", campaignID), attribute.Bool("claim.batched", c.batcher != nil)))
+	result, err := c.claim(ctx, campaignID, userID, now)
+	if err == nil {
+		span.SetAttributes(attribute.String("claim.status", fmt.Sprint(result[0])))
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+func (c *Client) claim(ctx context.Context, campaignID int64, userID string, now time.Time) ([]interface{}, error) {
+	layout, err := c.campaignLayout(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	home := HomeShard(userID, layout.Shards)
+	var result []interface{}
+	if c.batcher != nil {
+		result, err = c.batcher.claim(ctx, layout, campaignID, home, userID)
+	} else {
+		var results [][]interface{}
+		results, err = c.claimHome(ctx, layout, campaignID, home, []string{userID}, now)
+		if err == nil {
+			result = results[0]
+		}
+	}
+	if err != nil || result[0] != "RESERVED" {
+		return result, err
+	}
+
+	for i := 1; i < layout.Shards; i++ {
+		shard := (home + i) % layout.Shards
+		result, err = c.claimNeighbour(ctx, layout, campaignID, shard, userID, now)
+		if err == nil && result[0] == "OK" {
+			err = ctx.Err()
+		}
+		if err != nil {
+			metrics.IncClaimShardFallback("error")
+			if abandonErr := c.abandonFallback(ctx, layout, campaignID, home, shard, userID); abandonErr != nil {
+				metrics.IncClaimShardFallback("reservation_kept")
+			}
+			return nil, err
+		}
+		if result[0] == "OK" {
+			metrics.IncClaimShardFallback("claimed")
+			return result, nil
+		}
+		if result[0] != "SOLD_OUT" {
+			break
+		}
+	}
+	metrics.IncClaimShardFallback("released")
+	ctx, cancel := detach(ctx)
+	defer cancel()
+	if err := c.dropReservation(ctx, campaignID, home, userID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// releaseTimeout bounds the clean-up of an abandoned claim, which runs
+// detached from the caller's context.
+const releaseTimeout = time.Second
+
+// detach returns a context that keeps ctx's values, such as its span, but
+// not its cancellation, so clean-up outlives a caller that left.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+}
+
+// abandonFallback undoes a neighbour-shard fallback that failed or whose
+// caller left: the packet the neighbour may have handed out goes back, even
+// if its reply was lost, and then the user's reservation on the home shard is
+// dropped so they can open again. If the neighbour cannot be cleaned up the
+// reservation is kept, so the user cannot end up with two packets.
+func (c *Client) abandonFallback(ctx context.Context, layout *campaignLayout, campaignID int64, home, shard int, userID string) error {
+	ctx, cancel := detach(ctx)
+	defer cancel()
+	if _, err := c.releasePacket(ctx, layout, campaignID, shard, userID); err != nil {
+		return err
+	}
+	return c.dropReservation(ctx, campaignID, home, userID)
+}
+
+// dropReservation removes the user from the shard's opened set.
+func (c *Client) dropReservation(ctx context.Context, campaignID int64, shard int, userID string) error {
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("release_claim_reservation", time.Since(start)) }()
+	return c.rdb.SRem(ctx, c.shardKey(campaignID, shard, "opened"), userID).Err()
+}
+
+// claimHome claims for users whose home is the shard, in one script call. In
+// a sharded campaign, users who find the shard sold out are reserved there.
+func (c *Client) claimHome(ctx context.Context, layout *campaignLayout, campaignID int64, shard int, users []string, now time.Time) ([][]interface{}, error) {
+	opened := c.shardKey(campaignID, shard, "opened")
+	window := c.shardKey(campaignID, shard, "window")
+	leaderboard := c.shardKey(campaignID, shard, "leaderboard")
+	reserve := layout.Shards > 1
+	if layout.Mode == ClaimModeQueue {
+		args := make([]interface{}, 0, 2+len(users))
+		args = append(args, now.Unix(), reserve)
+		for _, user := range users {
+			args = append(args, user)
+		}
+		return c.runClaims(ctx, c.claimQueue, "run_claim_queue_script",
+			[]string{window, leaderboard, c.shardKey(campaignID, shard, "queue"), opened}, args, len(users))
+	}
+	args := make([]interface{}, 0, 3+len(users)+len(layout.amounts))
+	args = append(args, now.Unix(), reserve, len(users))
+	for _, user := range users {
+		args = append(args, user)
+	}
+	keys, args := c.appendTiers(campaignID, shard, layout.amounts, []string{opened, window, leaderboard}, args)
+	return c.runClaims(ctx, c.claimScript, "run_claim_script", keys, args, len(users))
+}
+
+// releaseClaim hands back the packet that claimHome gave the user on the
+// shard, or drops the user's reservation there, and reports whether anything
+// was released.
+func (c *Client) releaseClaim(ctx context.Context, layout *campaignLayout, campaignID int64, shard int, userID string, reply []interface{}) (bool, error) {
+	switch reply[0] {
+	case "RESERVED":
+		start := time.Now()
+		defer func() { metrics.ObserveRedisOperation("release_claim_reservation", time.Since(start)) }()
+		n, err := c.rdb.SRem(ctx, c.shardKey(campaignID, shard, "opened"), userID).Result()
+		return n == 1, err
+	case "OK":
+		return c.releasePacket(ctx, layout, campaignID, shard, userID)
+	}
+	return false, nil
+}
+
+// releasePacket puts back the packet the shard gave the user, if any, and
+// removes the user from the shard's opened set and leaderboard. A returned
+// packet publishes StateEventRestocked, since the campaign may have been
+// cached as sold out.
+func (c *Client) releasePacket(ctx context.Context, layout *campaignLayout, campaignID int64, shard int, userID string) (bool, error) {
+	keys := []string{c.shardKey(campaignID, shard, "opened"), c.shardKey(campaignID, shard, "leaderboard")}
+	args := []interface{}{userID, layout.Mode}
+	if layout.Mode == ClaimModeQueue {
+		keys = append(keys, c.shardKey(campaignID, shard, "queue"))
+	} else {
+		keys, args = c.appendTiers(campaignID, shard, layout.amounts, keys, args)
+	}
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("run_release_claim_script", time.Since(start)) }()
+	n, err := c.release.Run(ctx, c.rdb, keys, args...).Int()
+	if err != nil || n != 1 {
+		return false, err
+	}
+	// Cached state falls back to its TTL if the event is lost.
+	_ = c.PublishCampaignState(ctx, campaignID, StateEventRestocked)
+	return true, nil
+}
+
+// claimNeighbour takes a packet from another shard for a user reserved in
+// their home shard.
+func (c *Client) claimNeighbour(ctx context.Context, layout *campaignLayout, campaignID int64, shard int, userID string, now time.Time) ([]interface{}, error) {
+	window := c.shardKey(campaignID, shard, "window")
+	leaderboard := c.shardKey(campaignID, shard, "leaderboard")
+	if layout.Mode == ClaimModeQueue {
+		results, err := c.runClaims(ctx, c.claimQueue, "run_claim_queue_shard_script",
+			[]string{window, leaderboard, c.shardKey(campaignID, shard, "queue")},
+			[]interface{}{now.Unix(), false, userID}, 1)
+		if err != nil {
+			return nil, err
+		}
+		return results[0], nil
+	}
+	keys, args := c.appendTiers(campaignID, shard, layout.amounts,
+		[]string{window, leaderboard},
+		[]interface{}{userID, now.Unix()})
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("run_claim_shard_script", time.Since(start)) }()
+	result, err := c.claimShard.Run(ctx, c.rdb, keys, args...).Result()
+	if err != nil {
+		return nil, err
+	}
+	return claimReply(result)
+}
+
+// appendTiers appends the shard's inventory keys to keys and the matching
+// amounts to args.
+func (c *Client) appendTiers(campaignID int64, shard int, amounts []int64, keys []string, args []interface{}) ([]string, []interface{}) {
+	for _, amount := range amounts {
+		keys = append(keys, c.shardInventoryKey(campaignID, shard, amount))
+		args = append(args, amount)
+	}
+	return keys, args
+}
+
+// runClaims runs a batch claim script and returns one reply per user.
+func (c *Client) runClaims(ctx context.Context, script *goRedis.Script, op string, keys []string, args []interface{}, users int) ([][]interface{}, error) {
 	start := time.Now()
-	defer metrics.ObserveRedisOperation("run_claim_script", time.Since(start))
-	result, err := c.claimScript.Run(ctx, c.rdb, keys, args...).Result()
+	defer func() { metrics.ObserveRedisOperation(op, time.Since(start)) }()
+	result, err := script.Run(ctx, c.rdb, keys, args...).Result()
 	if err != nil {
 		return nil, err
 	}
 	arr, ok := result.([]interface{})
-	if !ok || len(arr) != 2 {
+	if !ok || len(arr) != users {
+		return nil, fmt.Errorf("unexpected Lua script response: %v", result)
+	}
+	replies := make([][]interface{}, 0, users)
+	for _, item := range arr {
+		reply, err := claimReply(item)
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
+
+func claimReply(result interface{}) ([]interface{}, error) {
+	arr, ok := result.([]interface{})
+	if !ok || len(arr) != 3 {
 		return nil, fmt.Errorf("unexpected Lua script response: %v", result)
 	}
 	return arr, nil
 }
 
-// InitializeInventory seeds Redis with campaign inventory counters and clears opened set.
-func (c *Client) InitializeInventory(ctx context.Context, campaignID int64, inventory map[int]int) error {
+// InitializeInventory seeds Redis with the campaign's packets and resets the
+// opened sets and leaderboards to the users in claimed, each with the amount
+// they won, on their home shards. Inventory maps amounts in minor units to
+// packet counts, which are split across shards by ShardCount. Counter mode
+// stores a counter per tier; queue mode pushes the shard's packets to its
+// queue in random order.
+func (c *Client) InitializeInventory(ctx context.Context, campaignID int64, inventory map[int64]int, layout Layout, claimed map[string]int64) error {
 	start := time.Now()
-	defer metrics.ObserveRedisOperation("initialize_inventory", time.Since(start))
-	pipe := c.rdb.TxPipeline()
-	pipe.Del(ctx, c.OpenedKey(campaignID))
-	pipe.Del(ctx, c.AmountsKey(campaignID))
-	for amount, count := range inventory {
-		pipe.Set(ctx, c.InventoryKey(campaignID, amount), count, 0)
-		pipe.SAdd(ctx, c.AmountsKey(campaignID), amount)
-	}
-	_, err := pipe.Exec(ctx)
-	return err
+	defer func() { metrics.ObserveRedisOperation("initialize_inventory", time.Since(start)) }()
+	c.forgetLayout(campaignID)
+	winners := make([][]goRedis.Z, layout.Shards)
+	for userID, amount := range claimed {
+		home := HomeShard(userID, layout.Shards)
+		winners[home] = append(winners[home], goRedis.Z{Score: float64(amount), Member: userID})
+	}
+	for shard := 0; shard < layout.Shards; shard++ {
+		counts := make(map[int64]int, len(inventory))
+		for amount, count := range inventory {
+			counts[amount] = ShardCount(count, shard, layout.Shards)
+		}
+		pipe := c.rdb.TxPipeline()
+		pipe.Del(ctx, c.shardKey(campaignID, shard, "opened"))
+		pipe.Del(ctx, c.shardKey(campaignID, shard, "leaderboard"))
+		pipe.Del(ctx, c.shardKey(campaignID, shard, "queue"))
+		for i := 0; i < len(winners[shard]); i += queuePushBatch {
+			batch := winners[shard][i:min(i+queuePushBatch, len(winners[shard]))]
+			users := make([]interface{}, len(batch))
+			for j, z := range batch {
+				users[j] = z.Member
+			}
+			pipe.SAdd(ctx, c.shardKey(campaignID, shard, "opened"), users...)
+			pipe.ZAdd(ctx, c.shardKey(campaignID, shard, "leaderboard"), batch...)
+		}
+		if shard == 0 {
+			pipe.Del(ctx, c.AmountsKey(campaignID))
+			for amount := range inventory {
+				pipe.SAdd(ctx, c.AmountsKey(campaignID), amount)
+			}
+		}
+		if layout.Mode == ClaimModeQueue {
+			packets := shuffledPackets(counts)
+			for len(packets) > 0 {
+				n := min(len(packets), queuePushBatch)
+				pipe.RPush(ctx, c.shardKey(campaignID, shard, "queue"), packets[:n]...)
+				packets = packets[n:]
+			}
+		} else {
+			for amount, count := range counts {
+				pipe.Set(ctx, c.shardInventoryKey(campaignID, shard, amount), count, 0)
+			}
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// SetCampaignWindow stores the active window meta in Redis.
-func (c *Client) SetCampaignWindow(ctx context.Context, campaignID int64, start, end time.Time) error {
+// SetCampaignWindow stores the active window, currency and rules JSON in
+// every shard. Shard 0 also records the layout and is written last, because
+// its window marks the campaign as primed. StateEventPrimed is published
+// afterwards.
+func (c *Client) SetCampaignWindow(ctx context.Context, campaignID int64, start, end time.Time, currency, rules string, layout Layout) error {
 	startTime := time.Now()
-	defer metrics.ObserveRedisOperation("set_campaign_window", time.Since(startTime))
-	key := c.CampaignWindowKey(campaignID)
-	return c.rdb.HSet(ctx, key, map[string]interface{}{
-		"start": start.Unix(),
-		"end":   end.Unix(),
-	}).Err()
+	defer func() { metrics.ObserveRedisOperation("set_campaign_window", time.Since(startTime)) }()
+	for shard := layout.Shards - 1; shard >= 0; shard-- {
+		fields := map[string]interface{}{
+			"start":    start.Unix(),
+			"end":      end.Unix(),
+			"currency": currency,
+		}
+		if shard == 0 {
+			fields["rules"] = rules
+			fields["shards"] = layout.Shards
+			fields["mode"] = layout.Mode
+		}
+		if err := c.rdb.HSet(ctx, c.shardKey(campaignID, shard, "window"), fields).Err(); err != nil {
+			return err
+		}
+	}
+	// Cached state falls back to its TTL if the event is lost.
+	_ = c.PublishCampaignState(ctx, campaignID, StateEventPrimed)
+	return nil
+}
+
+// CampaignPrimed reports whether the campaign's window key exists in Redis.
+func (c *Client) CampaignPrimed(ctx context.Context, campaignID int64) (bool, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("campaign_primed", time.Since(start)) }()
+	n, err := c.rdb.Exists(ctx, c.CampaignWindowKey(campaignID)).Result()
+	return n == 1, err
+}
+
+// FreezeCampaign marks every shard window frozen so the claim scripts reject
+// further opens, and publishes StateEventFrozen. It reports false and writes
+// nothing if the campaign has no window, because it was never primed or its
+// keys expired: freezing would create a window key holding only the flag.
+func (c *Client) FreezeCampaign(ctx context.Context, campaignID int64) (bool, error) {
+	primed, err := c.CampaignPrimed(ctx, campaignID)
+	if err != nil || !primed {
+		return false, err
+	}
+	layout, err := c.campaignLayout(ctx, campaignID)
+	if err != nil {
+		return false, err
+	}
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("freeze_campaign", time.Since(start)) }()
+	for shard := 0; shard < layout.Shards; shard++ {
+		if err := c.rdb.HSet(ctx, c.shardKey(campaignID, shard, "window"), "frozen", 1).Err(); err != nil {
+			return false, err
+		}
+	}
+	_ = c.PublishCampaignState(ctx, campaignID, StateEventFrozen)
+	return true, nil
+}
+
+// ExpireCampaignKeys sets a TTL on every Redis key that belongs to the campaign.
+func (c *Client) ExpireCampaignKeys(ctx context.Context, campaignID int64, ttl time.Duration) error {
+	layout, err := c.campaignLayout(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("expire_campaign_keys", time.Since(start)) }()
+	pipe := c.rdb.Pipeline()
+	for shard := 0; shard < layout.Shards; shard++ {
+		for _, amount := range layout.amounts {
+			pipe.Expire(ctx, c.shardInventoryKey(campaignID, shard, amount), ttl)
+		}
+		pipe.Expire(ctx, c.shardKey(campaignID, shard, "queue"), ttl)
+		pipe.Expire(ctx, c.shardKey(campaignID, shard, "opened"), ttl)
+		pipe.Expire(ctx, c.shardKey(campaignID, shard, "leaderboard"), ttl)
+		pipe.Expire(ctx, c.shardKey(campaignID, shard, "window"), ttl)
+	}
+	pipe.Expire(ctx, c.AmountsKey(campaignID), ttl)
+	pipe.Expire(ctx, c.QueueTicketsKey(campaignID), ttl)
+	pipe.Expire(ctx, c.QueueSeqKey(campaignID), ttl)
+	_, err = pipe.Exec(ctx)
+	c.forgetLayout(campaignID)
+	return err
+}
+
+// RemainingPackets counts the packets a campaign has left across its shards:
+// the tier counters in counter mode, the queue lengths in queue mode.
+func (c *Client) RemainingPackets(ctx context.Context, campaignID int64) (int64, error) {
+	layout, err := c.campaignLayout(ctx, campaignID)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("remaining_packets", time.Since(start)) }()
+	pipe := c.rdb.Pipeline()
+	var counters []*goRedis.StringCmd
+	var queues []*goRedis.IntCmd
+	for shard := 0; shard < layout.Shards; shard++ {
+		if layout.Mode == ClaimModeQueue {
+			queues = append(queues, pipe.LLen(ctx, c.shardKey(campaignID, shard, "queue")))
+			continue
+		}
+		for _, amount := range layout.amounts {
+			counters = append(counters, pipe.Get(ctx, c.shardInventoryKey(campaignID, shard, amount)))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, goRedis.Nil) {
+		return 0, err
+	}
+	var remaining int64
+	for _, cmd := range queues {
+		remaining += cmd.Val()
+	}
+	for _, cmd := range counters {
+		// A missing counter has no packets left.
+		if n, err := cmd.Int64(); err == nil && n > 0 {
+			remaining += n
+		}
+	}
+	return remaining, nil
+}
+
+// HasOpened reports whether the user is recorded in the opened set of their home shard.
+func (c *Client) HasOpened(ctx context.Context, campaignID int64, userID string) (bool, error) {
+	layout, err := c.campaignLayout(ctx, campaignID)
+	if err != nil {
+		return false, err
+	}
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("has_opened", time.Since(start)) }()
+	return c.rdb.SIsMember(ctx, c.shardKey(campaignID, HomeShard(userID, layout.Shards), "opened"), userID).Result()
+}
+
+// TopWinners returns the limit highest claims of a campaign. Equal amounts are
+// ordered by shard, then by user id, descending, as ZREVRANGE does.
+func (c *Client) TopWinners(ctx context.Context, campaignID int64, limit int) ([]LeaderboardEntry, error) {
+	layout, err := c.campaignLayout(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("top_winners", time.Since(start)) }()
+	type winner struct {
+		shard int
+		order int
+		entry LeaderboardEntry
+	}
+	var winners []winner
+	for shard := 0; shard < layout.Shards; shard++ {
+		members, err := c.rdb.ZRevRangeWithScores(ctx, c.shardKey(campaignID, shard, "leaderboard"), 0, int64(limit)-1).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i, m := range members {
+			userID, _ := m.Member.(string)
+			winners = append(winners, winner{shard: shard, order: i, entry: LeaderboardEntry{UserID: userID, Amount: int64(m.Score)}})
+		}
+	}
+	sort.Slice(winners, func(i, j int) bool {
+		a, b := winners[i], winners[j]
+		if a.entry.Amount != b.entry.Amount {
+			return a.entry.Amount > b.entry.Amount
+		}
+		if a.shard != b.shard {
+			return a.shard < b.shard
+		}
+		return a.order < b.order
+	})
+	if len(winners) > limit {
+		winners = winners[:limit]
+	}
+	entries := make([]LeaderboardEntry, 0, len(winners))
+	for i, w := range winners {
+		w.entry.Rank = int64(i) + 1
+		entries = append(entries, w.entry)
+	}
+	return entries, nil
+}
+
+// WinnerRank returns the user's leaderboard entry, or nil if they have not
+// claimed. Ranks follow the order of TopWinners.
+func (c *Client) WinnerRank(ctx context.Context, campaignID int64, userID string) (*LeaderboardEntry, error) {
+	layout, err := c.campaignLayout(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("winner_rank", time.Since(start)) }()
+	for shard := 0; shard < layout.Shards; shard++ {
+		key := c.shardKey(campaignID, shard, "leaderboard")
+		pipe := c.rdb.Pipeline()
+		rank := pipe.ZRevRank(ctx, key, userID)
+		score := pipe.ZScore(ctx, key, userID)
+		if _, err := pipe.Exec(ctx); err != nil {
+			if errors.Is(err, goRedis.Nil) {
+				continue
+			}
+			return nil, err
+		}
+		entry := &LeaderboardEntry{Rank: rank.Val() + 1, UserID: userID, Amount: int64(score.Val())}
+		// Other shards rank ahead with their higher amounts; shards before
+		// this one also with their equal amounts.
+		for other := 0; other < layout.Shards; other++ {
+			if other == shard {
+				continue
+			}
+			min := fmt.Sprintf("(%d", entry.Amount)
+			if other < shard {
+				min = fmt.Sprintf("%d", entry.Amount)
+			}
+			n, err := c.rdb.ZCount(ctx, c.shardKey(campaignID, other, "leaderboard"), min, "+inf").Result()
+			if err != nil {
+				return nil, err
+			}
+			entry.Rank += n
+		}
+		return entry, nil
+	}
+	return nil, nil
+}
+
+// AcquireLock takes the lease lock for owner, or renews it if owner already
+// holds it, and reports whether owner holds it afterwards.
+func (c *Client) AcquireLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("acquire_lock", time.Since(start)) }()
+	held, err := c.lockAcquire.Run(ctx, c.rdb, []string{key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return held == 1, nil
 }
 
+// ReleaseLock drops the lease lock if owner still holds it.
+func (c *Client) ReleaseLock(ctx context.Context, key, owner string) error {
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("release_lock", time.Since(start)) }()
+	return c.lockRelease.Run(ctx, c.rdb, []string{key}, owner).Err()
+}
+
+// Every campaign key carries the {campaign:<id>} hash tag, so a campaign's
+// keys land on one Redis Cluster slot and the claim script can touch them all.
+// Sharded campaigns keep shard 0 under that tag and put shard n under
+// {campaign:<id>:n}, so each shard can live on a different node.
+
 // OpenedKey returns the Redis key that tracks which users already opened a campaign.
 func (c *Client) OpenedKey(campaignID int64) string {
-	return fmt.Sprintf("campaign:%d:opened", campaignID)
+	return fmt.Sprintf("{campaign:%d}:opened", campaignID)
 }
 
-// InventoryKey returns the Redis key used to store inventory per amount.
-func (c *Client) InventoryKey(campaignID int64, amount int) string {
-	return fmt.Sprintf("campaign:%d:inv:%d", campaignID, amount)
+// InventoryKey returns the Redis key used to store inventory per amount in minor units.
+func (c *Client) InventoryKey(campaignID int64, amount int64) string {
+	return fmt.Sprintf("{campaign:%d}:inv:%d", campaignID, amount)
 }
 
 // AmountsKey stores the configured amounts for a campaign.
 func (c *Client) AmountsKey(campaignID int64) string {
-	return fmt.Sprintf("campaign:%d:amounts", campaignID)
+	return fmt.Sprintf("{campaign:%d}:amounts", campaignID)
+}
+
+// LeaderboardKey stores the campaign's winners scored by amount.
+func (c *Client) LeaderboardKey(campaignID int64) string {
+	return fmt.Sprintf("{campaign:%d}:leaderboard", campaignID)
+}
+
+// WarmLockKey is the lease held while a campaign is primed.
+func (c *Client) WarmLockKey(campaignID int64) string {
+	return fmt.Sprintf("{campaign:%d}:warm_lock", campaignID)
 }
 
 // CampaignWindowKey stores the start and end timestamps.
 func (c *Client) CampaignWindowKey(campaignID int64) string {
-	return fmt.Sprintf("campaign:%d:window", campaignID)
+	return fmt.Sprintf("{campaign:%d}:window", campaignID)
+}
+
+func (c *Client) shardKey(campaignID int64, shard int, suffix string) string {
+	if shard == 0 {
+		return fmt.Sprintf("{campaign:%d}:%s", campaignID, suffix)
+	}
+	return fmt.Sprintf("{campaign:%d:%d}:%s", campaignID, shard, suffix)
+}
+
+func (c *Client) shardInventoryKey(campaignID int64, shard int, amount int64) string {
+	return c.shardKey(campaignID, shard, fmt.Sprintf("inv:%d", amount))
 }
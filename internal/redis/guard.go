@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	goRedis "github.com/redis/go-redis/v9"
+
+	"redpacket/internal/resilience"
+)
+
+// guardHook passes every command through the client's resilience.Guard.
+// Single commands get the guard's deadline; pipelines, which prime whole
+// inventories, keep the caller's.
+type guardHook struct {
+	guard *resilience.Guard
+}
+
+func (h guardHook) DialHook(next goRedis.DialHook) goRedis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h guardHook) ProcessHook(next goRedis.ProcessHook) goRedis.ProcessHook {
+	return func(ctx context.Context, cmd goRedis.Cmder) error {
+		callCtx, done, err := h.guard.Acquire(ctx)
+		if err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		err = next(callCtx, cmd)
+		done(isFailure(err))
+		return err
+	}
+}
+
+func (h guardHook) ProcessPipelineHook(next goRedis.ProcessPipelineHook) goRedis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goRedis.Cmder) error {
+		_, done, err := h.guard.Acquire(ctx)
+		if err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		err = next(ctx, cmds)
+		done(isFailure(err))
+		return err
+	}
+}
+
+// isFailure tells Redis being unreachable or slow apart from replies such as
+// a missing key or NOSCRIPT, and from callers giving up.
+func isFailure(err error) bool {
+	var reply goRedis.Error
+	return err != nil && !errors.Is(err, goRedis.Nil) && !errors.As(err, &reply) && !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"redpacket/internal/observability/metrics"
+)
+
+var legacyKeyRe = regexp.MustCompile(`^campaign:(\d+):(.+)$`)
+
+// LegacyKeyRename is one campaign key moved to its hash-tagged name.
+type LegacyKeyRename struct {
+	From string
+	To   string
+}
+
+// RenameLegacyKeys moves campaign keys written before hash tags were
+// introduced, such as campaign:42:opened, to their {campaign:42}:opened form.
+// It must run against a standalone node before switching to Redis Cluster,
+// since cross-slot renames are rejected there. Keys whose new name already
+// exists are left in place. With dryRun set nothing is renamed.
+func (c *Client) RenameLegacyKeys(ctx context.Context, dryRun bool) ([]LegacyKeyRename, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("rename_legacy_keys", time.Since(start)) }()
+	var renamed []LegacyKeyRename
+	iter := c.rdb.Scan(ctx, 0, "campaign:*", 500).Iterator()
+	for iter.Next(ctx) {
+		m := legacyKeyRe.FindStringSubmatch(iter.Val())
+		if m == nil {
+			continue
+		}
+		rename := LegacyKeyRename{From: m[0], To: fmt.Sprintf("{campaign:%s}:%s", m[1], m[2])}
+		if !dryRun {
+			ok, err := c.rdb.RenameNX(ctx, rename.From, rename.To).Result()
+			if err != nil {
+				return renamed, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		renamed = append(renamed, rename)
+	}
+	return renamed, iter.Err()
+}
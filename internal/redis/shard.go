@@ -0,0 +1,157 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// MaxShards bounds how many inventory shards a campaign may be split into.
+const MaxShards = 64
+
+// Claim modes select how a campaign's packets are stored. Counter mode keeps
+// a counter per tier and picks a random tier with packets left on each claim.
+// Queue mode shuffles every packet into a list when the campaign is primed, so
+// a claim is a single LPOP.
+const (
+	ClaimModeCounter = "counter"
+	ClaimModeQueue   = "queue"
+)
+
+// layoutCacheTTL is how long a campaign's layout is reused before being read
+// again, so layouts of ended campaigns leave the cache.
+const layoutCacheTTL = 10 * time.Minute
+
+// maxCachedLayouts bounds the layout cache. The cache is cleared when it
+// fills up.
+const maxCachedLayouts = 10000
+
+// queuePushBatch caps the members sent by one RPUSH, SADD or ZADD when
+// priming a campaign.
+const queuePushBatch = 10000
+
+// Layout describes how a campaign's inventory is stored in Redis.
+type Layout struct {
+	Shards int
+	Mode   string
+}
+
+// campaignLayout is what a claim needs to know before running the script.
+type campaignLayout struct {
+	Layout
+	amounts []int64
+}
+
+type cachedLayout struct {
+	layout    *campaignLayout
+	expiresAt time.Time
+}
+
+// HomeShard returns the shard whose opened set records the user. It is stable
+// for a user and spreads users evenly across shards.
+func HomeShard(userID string, shards int) int {
+	if shards <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(userID))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// ShardCount returns how many of count packets the shard holds. The
+// remainder goes to the lowest shards.
+func ShardCount(count, shard, shards int) int {
+	n := count / shards
+	if shard < count%shards {
+		n++
+	}
+	return n
+}
+
+// campaignLayout returns the campaign's tier amounts and shard count from
+// shard 0, reading them on first use and again after layoutCacheTTL. A
+// campaign that is not fully primed is not cached.
+func (c *Client) campaignLayout(ctx context.Context, campaignID int64) (*campaignLayout, error) {
+	start := time.Now()
+	c.layoutsMu.RLock()
+	cached, ok := c.layouts[campaignID]
+	c.layoutsMu.RUnlock()
+	if ok && start.Before(cached.expiresAt) {
+		return cached.layout, nil
+	}
+	defer func() { metrics.ObserveRedisOperation("load_campaign_layout", time.Since(start)) }()
+	pipe := c.rdb.Pipeline()
+	members := pipe.SMembers(ctx, c.AmountsKey(campaignID))
+	window := pipe.HMGet(ctx, c.CampaignWindowKey(campaignID), "start", "shards", "mode")
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	layout := &campaignLayout{
+		Layout:  Layout{Shards: 1, Mode: ClaimModeCounter},
+		amounts: make([]int64, 0, len(members.Val())),
+	}
+	for _, raw := range members.Val() {
+		amount, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("campaign %d has invalid amount %q", campaignID, raw)
+		}
+		layout.amounts = append(layout.amounts, amount)
+	}
+	fields := window.Val()
+	if fields[0] == nil {
+		// Without the window there is nothing to claim yet, and the claim
+		// script answers CAMPAIGN_NOT_FOUND from shard 0.
+		return layout, nil
+	}
+	// Windows primed before sharding have no shard count or mode.
+	if raw, ok := fields[1].(string); ok {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > MaxShards {
+			return nil, fmt.Errorf("campaign %d has invalid shard count %q", campaignID, raw)
+		}
+		layout.Shards = n
+	}
+	if raw, ok := fields[2].(string); ok {
+		if raw != ClaimModeCounter && raw != ClaimModeQueue {
+			return nil, fmt.Errorf("campaign %d has invalid claim mode %q", campaignID, raw)
+		}
+		layout.Mode = raw
+	}
+	if len(layout.amounts) > 0 {
+		c.layoutsMu.Lock()
+		if len(c.layouts) >= maxCachedLayouts {
+			c.layouts = make(map[int64]cachedLayout)
+		}
+		c.layouts[campaignID] = cachedLayout{layout: layout, expiresAt: start.Add(layoutCacheTTL)}
+		c.layoutsMu.Unlock()
+	}
+	return layout, nil
+}
+
+// forgetLayout drops the campaign's cached layout.
+func (c *Client) forgetLayout(campaignID int64) {
+	c.layoutsMu.Lock()
+	delete(c.layouts, campaignID)
+	c.layoutsMu.Unlock()
+}
+
+// shuffledPackets returns count packets of each amount in random order.
+func shuffledPackets(inventory map[int64]int) []interface{} {
+	total := 0
+	for _, count := range inventory {
+		total += count
+	}
+	packets := make([]interface{}, 0, total)
+	for amount, count := range inventory {
+		for i := 0; i < count; i++ {
+			packets = append(packets, amount)
+		}
+	}
+	rand.Shuffle(len(packets), func(i, j int) { packets[i], packets[j] = packets[j], packets[i] })
+	return packets
+}
@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	goRedis "github.com/redis/go-redis/v9"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// CampaignStateChannel is the pub/sub channel that announces changes to a
+// campaign's claimability, so API instances can drop cached state.
+const CampaignStateChannel = "campaign-state"
+
+// Campaign state events published on CampaignStateChannel.
+const (
+	StateEventPrimed    = "primed"
+	StateEventFrozen    = "frozen"
+	StateEventSoldOut   = "sold_out"
+	StateEventRestocked = "restocked"
+)
+
+// CampaignStateEvent is one message on CampaignStateChannel.
+type CampaignStateEvent struct {
+	CampaignID int64  `json:"campaign_id"`
+	Event      string `json:"event"`
+}
+
+// CampaignWindow is the claim window stored in a campaign's window key.
+type CampaignWindow struct {
+	Start    time.Time
+	End      time.Time
+	Currency string
+	Frozen   bool
+}
+
+// GetCampaignWindow reads the campaign's window, or returns nil if the
+// campaign is not primed.
+func (c *Client) GetCampaignWindow(ctx context.Context, campaignID int64) (*CampaignWindow, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("get_campaign_window", time.Since(start)) }()
+	fields, err := c.rdb.HMGet(ctx, c.CampaignWindowKey(campaignID), "start", "end", "currency", "frozen").Result()
+	if err != nil {
+		return nil, err
+	}
+	startRaw, _ := fields[0].(string)
+	endRaw, _ := fields[1].(string)
+	startUnix, err := strconv.ParseInt(startRaw, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	endUnix, err := strconv.ParseInt(endRaw, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	currency, _ := fields[2].(string)
+	frozen, _ := fields[3].(string)
+	return &CampaignWindow{
+		Start:    time.Unix(startUnix, 0),
+		End:      time.Unix(endUnix, 0),
+		Currency: currency,
+		Frozen:   frozen == "1",
+	}, nil
+}
+
+// PublishCampaignState announces a campaign state change.
+func (c *Client) PublishCampaignState(ctx context.Context, campaignID int64, event string) error {
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("publish_campaign_state", time.Since(start)) }()
+	payload, err := json.Marshal(CampaignStateEvent{CampaignID: campaignID, Event: event})
+	if err != nil {
+		return err
+	}
+	return c.rdb.Publish(ctx, CampaignStateChannel, payload).Err()
+}
+
+// SubscribeCampaignState delivers events from CampaignStateChannel to
+// onEvent until ctx is done. onSubscribe runs whenever the subscription is
+// established, including after a reconnect, since events may have been
+// missed in between.
+func (c *Client) SubscribeCampaignState(ctx context.Context, onSubscribe func(), onEvent func(CampaignStateEvent)) error {
+	sub := c.rdb.Subscribe(ctx, CampaignStateChannel)
+	defer sub.Close()
+	for {
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, goRedis.ErrClosed) {
+				return err
+			}
+			// go-redis reconnects and resubscribes on the next Receive.
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		switch m := msg.(type) {
+		case *goRedis.Subscription:
+			if m.Kind == "subscribe" {
+				onSubscribe()
+			}
+		case *goRedis.Message:
+			var event CampaignStateEvent
+			if err := json.Unmarshal([]byte(m.Payload), &event); err == nil {
+				onEvent(event)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	goRedis "github.com/redis/go-redis/v9"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// IssueQueueTicket returns the user's waiting room ticket for the campaign,
+// issuing the next one on the first call. Tickets start at 1.
+func (c *Client) IssueQueueTicket(ctx context.Context, campaignID int64, userID string) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("issue_queue_ticket", time.Since(start)) }()
+	return c.queueTicket.Run(ctx, c.rdb, []string{c.QueueTicketsKey(campaignID), c.QueueSeqKey(campaignID)}, userID).Int64()
+}
+
+// QueueTicketsIssued returns how many waiting room tickets the campaign has issued.
+func (c *Client) QueueTicketsIssued(ctx context.Context, campaignID int64) (int64, error) {
+	start := time.Now()
+	defer func() { metrics.ObserveRedisOperation("queue_tickets_issued", time.Since(start)) }()
+	n, err := c.rdb.Get(ctx, c.QueueSeqKey(campaignID)).Int64()
+	if errors.Is(err, goRedis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// QueueTicketsKey maps the users in a campaign's waiting room to their tickets.
+func (c *Client) QueueTicketsKey(campaignID int64) string {
+	return c.shardKey(campaignID, 0, "wr:tickets")
+}
+
+// QueueSeqKey counts the waiting room tickets issued for a campaign.
+func (c *Client) QueueSeqKey(campaignID int64) string {
+	return c.shardKey(campaignID, 0, "wr:seq")
+}
@@ -0,0 +1,224 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"redpacket/internal/observability/metrics"
+)
+
+// saturatedRetryAfter is the wait suggested to callers turned away by a full
+// concurrency limit, which usually frees up within a second.
+const saturatedRetryAfter = time.Second
+
+// Reasons a call is rejected.
+const (
+	ReasonOpen      = "open"
+	ReasonSaturated = "saturated"
+)
+
+// ErrUnavailable matches every UnavailableError.
+var ErrUnavailable = errors.New("dependency unavailable")
+
+// UnavailableError is returned instead of calling a dependency whose breaker
+// is open (ReasonOpen) or whose concurrency limit is reached
+// (ReasonSaturated). RetryAfter is a hint for when a new call may be admitted.
+type UnavailableError struct {
+	Dependency string
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Reason == ReasonSaturated {
+		return fmt.Sprintf("%s unavailable: too many calls in flight", e.Dependency)
+	}
+	return fmt.Sprintf("%s unavailable: circuit breaker open", e.Dependency)
+}
+
+// Is makes errors.Is(err, ErrUnavailable) report true.
+func (e *UnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// State is a circuit breaker state.
+type State int
+
+// Breaker states, ordered so the metric value grows with severity.
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "open"
+	}
+}
+
+// Config tunes a Guard.
+type Config struct {
+	// Name labels the dependency in metrics, logs and errors.
+	Name string
+	// Timeout bounds each call; zero keeps the caller's deadline.
+	Timeout time.Duration
+	// MaxInFlight caps concurrent calls; zero means no cap.
+	MaxInFlight int
+	// FailureThreshold consecutive failures open the breaker.
+	FailureThreshold int
+	// Cooldown is how long an open breaker rejects calls before letting a
+	// single probe through.
+	Cooldown time.Duration
+	// Logger receives state transitions; nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Guard protects one dependency with a circuit breaker, a concurrency limit
+// and a per-call deadline. A nil Guard admits every call unchanged.
+type Guard struct {
+	cfg Config
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+	inFlight int
+}
+
+// Snapshot is a point-in-time view of a Guard.
+type Snapshot struct {
+	Dependency          string
+	State               State
+	ConsecutiveFailures int
+	InFlight            int
+	MaxInFlight         int
+	OpenedAt            *time.Time
+	RetryAfter          time.Duration
+}
+
+// NewGuard builds a closed Guard.
+func NewGuard(cfg Config) *Guard {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	g := &Guard{cfg: cfg}
+	metrics.SetBreakerState(cfg.Name, int(StateClosed))
+	metrics.SetDependencyInFlight(cfg.Name, 0)
+	return g
+}
+
+// Acquire admits a call or returns an *UnavailableError. An admitted call
+// runs with the returned context, which carries the call deadline, and must
+// then call done exactly once, reporting whether the dependency failed.
+// Errors that are the caller's fault, such as a missing row, are not
+// failures.
+func (g *Guard) Acquire(ctx context.Context) (context.Context, func(failed bool), error) {
+	if g == nil {
+		return ctx, func(bool) {}, nil
+	}
+	g.mu.Lock()
+	if g.state == StateOpen {
+		if wait := time.Until(g.openedAt.Add(g.cfg.Cooldown)); wait > 0 {
+			g.mu.Unlock()
+			return ctx, nil, g.reject(ReasonOpen, wait)
+		}
+		g.setState(StateHalfOpen)
+	}
+	probe := false
+	if g.state == StateHalfOpen {
+		if g.probing {
+			g.mu.Unlock()
+			return ctx, nil, g.reject(ReasonOpen, g.cfg.Cooldown)
+		}
+		g.probing, probe = true, true
+	}
+	if g.cfg.MaxInFlight > 0 && g.inFlight >= g.cfg.MaxInFlight {
+		if probe {
+			g.probing = false
+		}
+		g.mu.Unlock()
+		return ctx, nil, g.reject(ReasonSaturated, saturatedRetryAfter)
+	}
+	g.inFlight++
+	metrics.SetDependencyInFlight(g.cfg.Name, g.inFlight)
+	g.mu.Unlock()
+
+	cancel := context.CancelFunc(func() {})
+	if g.cfg.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, g.cfg.Timeout)
+	}
+	var once sync.Once
+	return ctx, func(failed bool) {
+		once.Do(func() {
+			cancel()
+			g.release(probe, failed)
+		})
+	}, nil
+}
+
+// Snapshot reports the Guard's current state.
+func (g *Guard) Snapshot() Snapshot {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := Snapshot{
+		Dependency:          g.cfg.Name,
+		State:               g.state,
+		ConsecutiveFailures: g.failures,
+		InFlight:            g.inFlight,
+		MaxInFlight:         g.cfg.MaxInFlight,
+	}
+	if g.state != StateClosed {
+		openedAt := g.openedAt
+		s.OpenedAt = &openedAt
+		s.RetryAfter = max(time.Until(openedAt.Add(g.cfg.Cooldown)), 0)
+	}
+	return s
+}
+
+func (g *Guard) release(probe, failed bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inFlight--
+	metrics.SetDependencyInFlight(g.cfg.Name, g.inFlight)
+	if probe {
+		g.probing = false
+	}
+	if !failed {
+		g.failures = 0
+		if probe {
+			g.setState(StateClosed)
+		}
+		return
+	}
+	g.failures++
+	if probe || (g.state == StateClosed && g.failures >= g.cfg.FailureThreshold) {
+		g.openedAt = time.Now()
+		g.setState(StateOpen)
+	}
+}
+
+// setState must be called with mu held.
+func (g *Guard) setState(s State) {
+	if g.state == s {
+		return
+	}
+	g.cfg.Logger.Warn("breaker state changed", slog.String("dependency", g.cfg.Name), slog.String("from", g.state.String()), slog.String("to", s.String()))
+	g.state = s
+	metrics.SetBreakerState(g.cfg.Name, int(s))
+}
+
+func (g *Guard) reject(reason string, retryAfter time.Duration) error {
+	metrics.IncDependencyRejection(g.cfg.Name, reason)
+	return &UnavailableError{Dependency: g.cfg.Name, Reason: reason, RetryAfter: retryAfter}
+}
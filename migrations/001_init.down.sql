@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS claim_log;
+DROP TABLE IF EXISTS campaign_inventory;
+DROP TABLE IF EXISTS campaign;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ledger_entry;
+DROP TABLE IF EXISTS ledger_txn;
+DROP TABLE IF EXISTS ledger_account;
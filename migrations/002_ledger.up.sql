@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS ledger_account (
+    id SERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE,
+    kind TEXT NOT NULL,
+    campaign_id INT,
+    user_id TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS ledger_txn (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    campaign_id INT NOT NULL,
+    claim_log_id INT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entry (
+    id BIGSERIAL PRIMARY KEY,
+    txn_id BIGINT NOT NULL REFERENCES ledger_txn (id),
+    account_id INT NOT NULL REFERENCES ledger_account (id),
+    direction TEXT NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS ledger_txn_campaign_idx ON ledger_txn (campaign_id);
+CREATE INDEX IF NOT EXISTS ledger_entry_txn_idx ON ledger_entry (txn_id);
+CREATE INDEX IF NOT EXISTS ledger_entry_account_idx ON ledger_entry (account_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS claim_log_campaign_user_idx;
+DROP INDEX IF EXISTS claim_log_user_id_idx;
+DROP TABLE IF EXISTS user_wallet;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_wallet (
+    user_id TEXT NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'CNY',
+    balance BIGINT NOT NULL DEFAULT 0,
+    claim_count INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, currency)
+);
+
+CREATE INDEX IF NOT EXISTS claim_log_user_id_idx ON claim_log (user_id, id);
+CREATE INDEX IF NOT EXISTS claim_log_campaign_user_idx ON claim_log (campaign_id, user_id);
@@ -0,0 +1,14 @@
+-- Whole-yuan amounts cannot represent fractional packets; those are truncated.
+UPDATE user_wallet SET balance = balance / 100;
+UPDATE ledger_account SET code = left(code, -length(':' || currency)) WHERE kind = 'user_wallet';
+ALTER TABLE ledger_account DROP COLUMN IF EXISTS currency;
+ALTER TABLE ledger_txn DROP COLUMN IF EXISTS currency;
+UPDATE ledger_entry SET amount = amount / 100;
+
+ALTER TABLE claim_log DROP COLUMN IF EXISTS currency;
+ALTER TABLE claim_log ALTER COLUMN amount TYPE INT USING (amount / 100)::INT;
+
+ALTER TABLE campaign_inventory DROP COLUMN IF EXISTS currency;
+ALTER TABLE campaign_inventory ALTER COLUMN amount TYPE INT USING (amount / 100)::INT;
+
+ALTER TABLE campaign DROP COLUMN IF EXISTS currency;
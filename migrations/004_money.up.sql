@@ -0,0 +1,32 @@
+-- Amounts move from whole yuan to minor units (fen) with an explicit currency.
+-- The guard keeps the conversion one-shot on databases adopted from before
+-- schema_migrations existed, where this file may already have run.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'campaign' AND column_name = 'currency'
+    ) THEN
+        ALTER TABLE campaign ADD COLUMN currency TEXT NOT NULL DEFAULT 'CNY';
+
+        ALTER TABLE campaign_inventory ALTER COLUMN amount TYPE BIGINT USING amount::BIGINT * 100;
+        ALTER TABLE campaign_inventory ADD COLUMN currency TEXT NOT NULL DEFAULT 'CNY';
+
+        ALTER TABLE claim_log ALTER COLUMN amount TYPE BIGINT USING amount::BIGINT * 100;
+        ALTER TABLE claim_log ADD COLUMN currency TEXT NOT NULL DEFAULT 'CNY';
+
+        UPDATE ledger_entry SET amount = amount * 100;
+        ALTER TABLE ledger_txn ADD COLUMN currency TEXT NOT NULL DEFAULT 'CNY';
+        ALTER TABLE ledger_account ADD COLUMN currency TEXT NOT NULL DEFAULT 'CNY';
+        UPDATE ledger_account SET code = code || ':CNY' WHERE kind = 'user_wallet';
+
+        UPDATE user_wallet SET balance = balance * 100;
+
+        ALTER TABLE campaign ALTER COLUMN currency DROP DEFAULT;
+        ALTER TABLE campaign_inventory ALTER COLUMN currency DROP DEFAULT;
+        ALTER TABLE claim_log ALTER COLUMN currency DROP DEFAULT;
+        ALTER TABLE ledger_txn ALTER COLUMN currency DROP DEFAULT;
+        ALTER TABLE ledger_account ALTER COLUMN currency DROP DEFAULT;
+        ALTER TABLE user_wallet ALTER COLUMN currency DROP DEFAULT;
+    END IF;
+END $$;
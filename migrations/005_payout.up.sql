@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS payout (
+    id BIGSERIAL PRIMARY KEY,
+    claim_log_id BIGINT NOT NULL,
+    campaign_id INT NOT NULL,
+    user_id TEXT NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL UNIQUE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'retrying', 'succeeded', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    provider_ref TEXT,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS payout_due_idx ON payout (next_attempt_at) WHERE status IN ('pending', 'retrying');
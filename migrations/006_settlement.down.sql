@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS campaign_settlement_tier;
+DROP TABLE IF EXISTS campaign_settlement;
+ALTER TABLE campaign DROP COLUMN IF EXISTS frozen_at;
@@ -0,0 +1,21 @@
+ALTER TABLE campaign ADD COLUMN IF NOT EXISTS frozen_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS campaign_settlement (
+    campaign_id INT PRIMARY KEY,
+    currency TEXT NOT NULL,
+    funded_total BIGINT NOT NULL,
+    claimed_total BIGINT NOT NULL,
+    unclaimed_total BIGINT NOT NULL,
+    settled_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    event_published_at TIMESTAMP,
+    redis_released_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS campaign_settlement_tier (
+    campaign_id INT NOT NULL REFERENCES campaign_settlement (campaign_id),
+    amount BIGINT NOT NULL,
+    initial_total INT NOT NULL,
+    claimed_count INT NOT NULL,
+    unclaimed_count INT NOT NULL,
+    PRIMARY KEY (campaign_id, amount)
+);
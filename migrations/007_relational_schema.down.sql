@@ -0,0 +1,33 @@
+-- Orphaned rows moved aside by the up migration are left in the *_orphan tables.
+ALTER TABLE campaign_settlement DROP CONSTRAINT IF EXISTS campaign_settlement_campaign_fk;
+ALTER TABLE payout DROP CONSTRAINT IF EXISTS payout_campaign_fk;
+ALTER TABLE ledger_txn DROP CONSTRAINT IF EXISTS ledger_txn_campaign_fk;
+
+DROP INDEX IF EXISTS campaign_status_idx;
+DROP INDEX IF EXISTS claim_log_campaign_created_idx;
+
+ALTER TABLE claim_log
+    DROP CONSTRAINT IF EXISTS claim_log_campaign_fk,
+    ALTER COLUMN user_id DROP NOT NULL,
+    ALTER COLUMN campaign_id DROP NOT NULL,
+    ALTER COLUMN amount DROP NOT NULL,
+    ALTER COLUMN created_at DROP NOT NULL;
+
+ALTER TABLE campaign_inventory
+    DROP CONSTRAINT IF EXISTS campaign_inventory_counts_check,
+    DROP CONSTRAINT IF EXISTS campaign_inventory_amount_check,
+    DROP CONSTRAINT IF EXISTS campaign_inventory_campaign_amount_key,
+    DROP CONSTRAINT IF EXISTS campaign_inventory_campaign_fk,
+    ALTER COLUMN campaign_id DROP NOT NULL,
+    ALTER COLUMN amount DROP NOT NULL,
+    ALTER COLUMN initial_total DROP NOT NULL,
+    ALTER COLUMN opened_count DROP NOT NULL;
+
+ALTER TABLE campaign
+    DROP CONSTRAINT IF EXISTS campaign_status_check,
+    DROP CONSTRAINT IF EXISTS campaign_window_check,
+    DROP COLUMN IF EXISTS status,
+    ALTER COLUMN start_time TYPE TIMESTAMP USING start_time AT TIME ZONE 'UTC',
+    ALTER COLUMN end_time TYPE TIMESTAMP USING end_time AT TIME ZONE 'UTC',
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN name DROP NOT NULL;
@@ -0,0 +1,90 @@
+-- Rows that reference a missing campaign cannot satisfy the new foreign keys.
+-- They are moved aside rather than deleted so finance can still inspect them.
+CREATE TABLE IF NOT EXISTS campaign_inventory_orphan (LIKE campaign_inventory);
+CREATE TABLE IF NOT EXISTS claim_log_orphan (LIKE claim_log);
+
+WITH moved AS (
+    DELETE FROM campaign_inventory i
+    WHERE i.campaign_id IS NULL OR NOT EXISTS (SELECT 1 FROM campaign c WHERE c.id = i.campaign_id)
+    RETURNING i.*
+)
+INSERT INTO campaign_inventory_orphan SELECT * FROM moved;
+
+WITH moved AS (
+    DELETE FROM claim_log l
+    WHERE l.campaign_id IS NULL OR NOT EXISTS (SELECT 1 FROM campaign c WHERE c.id = l.campaign_id)
+    RETURNING l.*
+)
+INSERT INTO claim_log_orphan SELECT * FROM moved;
+
+-- Merge duplicate tiers into the oldest row before enforcing (campaign_id, amount).
+WITH dup AS (
+    SELECT campaign_id, amount, MIN(id) AS keep_id,
+           SUM(COALESCE(initial_total, 0)) AS initial_total,
+           SUM(COALESCE(opened_count, 0)) AS opened_count
+    FROM campaign_inventory
+    GROUP BY campaign_id, amount
+    HAVING COUNT(*) > 1
+)
+UPDATE campaign_inventory i
+SET initial_total = dup.initial_total, opened_count = dup.opened_count
+FROM dup
+WHERE i.id = dup.keep_id;
+
+DELETE FROM campaign_inventory i
+USING campaign_inventory keep
+WHERE i.campaign_id = keep.campaign_id AND i.amount = keep.amount AND i.id > keep.id;
+
+-- Backfill nullable columns.
+UPDATE campaign SET name = 'campaign-' || id WHERE name IS NULL;
+UPDATE campaign SET created_at = start_time WHERE created_at IS NULL;
+UPDATE campaign_inventory SET initial_total = 0 WHERE initial_total IS NULL;
+UPDATE campaign_inventory SET opened_count = 0 WHERE opened_count IS NULL;
+UPDATE claim_log SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE claim_log SET user_id = '' WHERE user_id IS NULL;
+
+-- Window columns were written as UTC wall-clock values.
+ALTER TABLE campaign
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN start_time TYPE TIMESTAMPTZ USING start_time AT TIME ZONE 'UTC',
+    ALTER COLUMN end_time TYPE TIMESTAMPTZ USING end_time AT TIME ZONE 'UTC',
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'scheduled',
+    ADD CONSTRAINT campaign_window_check CHECK (end_time > start_time),
+    ADD CONSTRAINT campaign_status_check CHECK (status IN ('scheduled', 'active', 'ended', 'settled'));
+
+UPDATE campaign c SET status = CASE
+    WHEN EXISTS (SELECT 1 FROM campaign_settlement s WHERE s.campaign_id = c.id) THEN 'settled'
+    WHEN c.end_time < NOW() THEN 'ended'
+    WHEN c.start_time <= NOW() THEN 'active'
+    ELSE 'scheduled'
+END;
+
+ALTER TABLE campaign_inventory
+    ALTER COLUMN campaign_id SET NOT NULL,
+    ALTER COLUMN amount SET NOT NULL,
+    ALTER COLUMN initial_total SET NOT NULL,
+    ALTER COLUMN opened_count SET NOT NULL,
+    ADD CONSTRAINT campaign_inventory_campaign_fk FOREIGN KEY (campaign_id) REFERENCES campaign (id),
+    ADD CONSTRAINT campaign_inventory_campaign_amount_key UNIQUE (campaign_id, amount),
+    ADD CONSTRAINT campaign_inventory_amount_check CHECK (amount > 0),
+    ADD CONSTRAINT campaign_inventory_counts_check CHECK (initial_total >= 0 AND opened_count >= 0);
+
+ALTER TABLE claim_log
+    ALTER COLUMN user_id SET NOT NULL,
+    ALTER COLUMN campaign_id SET NOT NULL,
+    ALTER COLUMN amount SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL,
+    ADD CONSTRAINT claim_log_campaign_fk FOREIGN KEY (campaign_id) REFERENCES campaign (id);
+
+-- claim_log_campaign_user_idx and claim_log_user_id_idx (003) already lead with
+-- campaign_id and user_id; this one serves campaign-wide scans ordered by time.
+CREATE INDEX IF NOT EXISTS claim_log_campaign_created_idx ON claim_log (campaign_id, created_at);
+CREATE INDEX IF NOT EXISTS campaign_status_idx ON campaign (status, end_time);
+
+ALTER TABLE ledger_txn
+    ADD CONSTRAINT ledger_txn_campaign_fk FOREIGN KEY (campaign_id) REFERENCES campaign (id);
+ALTER TABLE payout
+    ADD CONSTRAINT payout_campaign_fk FOREIGN KEY (campaign_id) REFERENCES campaign (id);
+ALTER TABLE campaign_settlement
+    ADD CONSTRAINT campaign_settlement_campaign_fk FOREIGN KEY (campaign_id) REFERENCES campaign (id);
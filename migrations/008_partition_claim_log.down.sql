@@ -0,0 +1,25 @@
+-- Rows in partitions that were already archived and dropped are not restored.
+ALTER SEQUENCE claim_log_id_seq OWNED BY NONE;
+
+CREATE TABLE claim_log_plain (
+    id BIGINT PRIMARY KEY DEFAULT nextval('claim_log_id_seq'),
+    user_id TEXT NOT NULL,
+    campaign_id INT NOT NULL,
+    amount BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO claim_log_plain (id, user_id, campaign_id, amount, currency, created_at)
+SELECT id, user_id, campaign_id, amount, currency, created_at AT TIME ZONE 'UTC'
+FROM claim_log;
+
+DROP TABLE claim_log;
+ALTER TABLE claim_log_plain RENAME TO claim_log;
+ALTER SEQUENCE claim_log_id_seq OWNED BY claim_log.id;
+
+ALTER TABLE claim_log
+    ADD CONSTRAINT claim_log_campaign_fk FOREIGN KEY (campaign_id) REFERENCES campaign (id);
+CREATE INDEX claim_log_user_id_idx ON claim_log (user_id, id);
+CREATE INDEX claim_log_campaign_user_idx ON claim_log (campaign_id, user_id);
+CREATE INDEX claim_log_campaign_created_idx ON claim_log (campaign_id, created_at);
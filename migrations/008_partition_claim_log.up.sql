@@ -0,0 +1,50 @@
+-- claim_log becomes a table range-partitioned by created_at with one partition
+-- per UTC day. Rows that predate the switch land in a single history
+-- partition; claim_log_default catches anything outside the created ranges and
+-- is drained by the partition manager when the matching day is created.
+-- Partitions are named claim_log_pYYYYMMDD (one day) and claim_log_hYYYYMMDD
+-- (history, upper bound exclusive) so the manager can derive their ranges.
+ALTER SEQUENCE claim_log_id_seq OWNED BY NONE;
+ALTER SEQUENCE claim_log_id_seq AS BIGINT;
+
+CREATE TABLE claim_log_partitioned (
+    id BIGINT NOT NULL DEFAULT nextval('claim_log_id_seq'),
+    user_id TEXT NOT NULL,
+    campaign_id INT NOT NULL,
+    amount BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+DO $$
+DECLARE
+    today TIMESTAMPTZ := date_trunc('day', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+    day TIMESTAMPTZ;
+BEGIN
+    EXECUTE format(
+        'CREATE TABLE %I PARTITION OF claim_log_partitioned FOR VALUES FROM (MINVALUE) TO (%L)',
+        'claim_log_h' || to_char(today AT TIME ZONE 'UTC', 'YYYYMMDD'), today);
+    FOR i IN 0..6 LOOP
+        day := today + make_interval(days => i);
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF claim_log_partitioned FOR VALUES FROM (%L) TO (%L)',
+            'claim_log_p' || to_char(day AT TIME ZONE 'UTC', 'YYYYMMDD'), day, day + INTERVAL '1 day');
+    END LOOP;
+END $$;
+
+CREATE TABLE claim_log_default PARTITION OF claim_log_partitioned DEFAULT;
+
+INSERT INTO claim_log_partitioned (id, user_id, campaign_id, amount, currency, created_at)
+SELECT id, user_id, campaign_id, amount, currency, created_at AT TIME ZONE 'UTC'
+FROM claim_log;
+
+DROP TABLE claim_log;
+ALTER TABLE claim_log_partitioned RENAME TO claim_log;
+ALTER SEQUENCE claim_log_id_seq OWNED BY claim_log.id;
+
+ALTER TABLE claim_log
+    ADD CONSTRAINT claim_log_campaign_fk FOREIGN KEY (campaign_id) REFERENCES campaign (id);
+CREATE INDEX claim_log_user_id_idx ON claim_log (user_id, id);
+CREATE INDEX claim_log_campaign_user_idx ON claim_log (campaign_id, user_id);
+CREATE INDEX claim_log_campaign_created_idx ON claim_log (campaign_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS campaign_stats_minute_tier;
+DROP TABLE IF EXISTS campaign_stats_minute;
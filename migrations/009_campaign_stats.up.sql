@@ -0,0 +1,41 @@
+CREATE TABLE campaign_stats_minute (
+    campaign_id INT NOT NULL REFERENCES campaign (id),
+    bucket TIMESTAMPTZ NOT NULL,
+    claims INT NOT NULL DEFAULT 0,
+    unique_users INT NOT NULL DEFAULT 0,
+    amount_sum BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (campaign_id, bucket)
+);
+
+CREATE TABLE campaign_stats_minute_tier (
+    campaign_id INT NOT NULL REFERENCES campaign (id),
+    bucket TIMESTAMPTZ NOT NULL,
+    amount BIGINT NOT NULL,
+    claims INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (campaign_id, bucket, amount)
+);
+
+-- Backfill from the claims persisted so far. A user counts as unique in the
+-- minute of their first claim in the campaign.
+INSERT INTO campaign_stats_minute (campaign_id, bucket, claims, amount_sum)
+SELECT campaign_id, date_trunc('minute', created_at), COUNT(*), SUM(amount)
+FROM claim_log
+GROUP BY campaign_id, date_trunc('minute', created_at);
+
+UPDATE campaign_stats_minute s
+SET unique_users = f.users
+FROM (
+    SELECT campaign_id, bucket, COUNT(*) AS users
+    FROM (
+        SELECT campaign_id, date_trunc('minute', MIN(created_at)) AS bucket
+        FROM claim_log
+        GROUP BY campaign_id, user_id
+    ) first_claims
+    GROUP BY campaign_id, bucket
+) f
+WHERE s.campaign_id = f.campaign_id AND s.bucket = f.bucket;
+
+INSERT INTO campaign_stats_minute_tier (campaign_id, bucket, amount, claims)
+SELECT campaign_id, date_trunc('minute', created_at), amount, COUNT(*)
+FROM claim_log
+GROUP BY campaign_id, date_trunc('minute', created_at), amount;
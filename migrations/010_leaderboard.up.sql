@@ -0,0 +1,7 @@
+CREATE TABLE campaign_leaderboard (
+    campaign_id INT NOT NULL REFERENCES campaign (id),
+    rank INT NOT NULL,
+    user_id TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    PRIMARY KEY (campaign_id, rank)
+);
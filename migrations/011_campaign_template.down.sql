@@ -0,0 +1,5 @@
+ALTER TABLE campaign
+    DROP COLUMN IF EXISTS template_id,
+    DROP COLUMN IF EXISTS rules;
+
+DROP TABLE IF EXISTS campaign_template;
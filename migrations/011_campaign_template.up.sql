@@ -0,0 +1,14 @@
+CREATE TABLE campaign_template (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    name_pattern TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    inventory JSONB NOT NULL,
+    rules JSONB NOT NULL DEFAULT '{}',
+    duration_seconds BIGINT NOT NULL CHECK (duration_seconds > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE campaign
+    ADD COLUMN rules JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN template_id INT REFERENCES campaign_template (id);
@@ -0,0 +1,6 @@
+ALTER TABLE campaign
+    DROP CONSTRAINT IF EXISTS campaign_schedule_occurrence_key,
+    DROP COLUMN IF EXISTS occurrence,
+    DROP COLUMN IF EXISTS schedule_id;
+
+DROP TABLE IF EXISTS campaign_schedule;
@@ -0,0 +1,15 @@
+CREATE TABLE campaign_schedule (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    template_id INT NOT NULL REFERENCES campaign_template (id),
+    cron TEXT NOT NULL,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- A schedule creates at most one campaign per occurrence, whichever replica runs it.
+ALTER TABLE campaign
+    ADD COLUMN schedule_id INT REFERENCES campaign_schedule (id),
+    ADD COLUMN occurrence TIMESTAMPTZ,
+    ADD CONSTRAINT campaign_schedule_occurrence_key UNIQUE (schedule_id, occurrence);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS campaign_unwarmed_idx;
+
+ALTER TABLE campaign DROP COLUMN IF EXISTS warmed_at;
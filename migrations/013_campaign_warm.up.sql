@@ -0,0 +1,6 @@
+ALTER TABLE campaign ADD COLUMN warmed_at TIMESTAMPTZ;
+
+-- Campaigns created so far were primed in Redis when they were created.
+UPDATE campaign SET warmed_at = created_at;
+
+CREATE INDEX campaign_unwarmed_idx ON campaign (start_time) WHERE warmed_at IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE campaign DROP COLUMN IF EXISTS inventory_shards;
@@ -0,0 +1,3 @@
+ALTER TABLE campaign
+    ADD COLUMN inventory_shards INT NOT NULL DEFAULT 1
+    CONSTRAINT campaign_inventory_shards_check CHECK (inventory_shards BETWEEN 1 AND 64);
@@ -0,0 +1 @@
+ALTER TABLE campaign DROP COLUMN IF EXISTS claim_mode;
@@ -0,0 +1,3 @@
+ALTER TABLE campaign
+    ADD COLUMN claim_mode TEXT NOT NULL DEFAULT 'counter'
+    CONSTRAINT campaign_claim_mode_check CHECK (claim_mode IN ('counter', 'queue'));
@@ -0,0 +1 @@
+ALTER TABLE campaign DROP COLUMN IF EXISTS admission_rate;
@@ -0,0 +1,3 @@
+ALTER TABLE campaign
+    ADD COLUMN admission_rate INT
+    CONSTRAINT campaign_admission_rate_check CHECK (admission_rate > 0);
@@ -0,0 +1,15 @@
+-- claim_dedupe holds one row per recorded claim, keyed by campaign and user,
+-- so a redelivered claim event is dropped before it writes anything.
+-- claim_log cannot carry this key: a unique index on a partitioned table must
+-- include the partition column, created_at, which differs between deliveries.
+CREATE TABLE claim_dedupe (
+    campaign_id INT NOT NULL REFERENCES campaign (id),
+    user_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (campaign_id, user_id)
+);
+
+INSERT INTO claim_dedupe (campaign_id, user_id, created_at)
+SELECT campaign_id, user_id, MIN(created_at)
+FROM claim_log
+GROUP BY campaign_id, user_id;
@@ -0,0 +1 @@
+ALTER TABLE ledger_txn ALTER COLUMN claim_log_id TYPE INT;
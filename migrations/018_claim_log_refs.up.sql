@@ -0,0 +1,10 @@
+-- ledger_txn.claim_log_id was INT while claim_log.id and payout.claim_log_id
+-- are BIGINT, so claim postings would fail once claim ids pass 2^31.
+--
+-- Neither reference gets a foreign key. Since 008, claim_log is partitioned
+-- and its primary key is (id, created_at): a foreign key needs a unique key on
+-- the referenced columns, and a unique key on a partitioned table must include
+-- the partition column, so claim_log.id alone cannot be referenced. Retention
+-- also detaches and drops old claim_log partitions while their ledger and
+-- payout rows are kept. The consumer writes all three rows in one transaction.
+ALTER TABLE ledger_txn ALTER COLUMN claim_log_id TYPE BIGINT;
@@ -1,8 +1,9 @@
 package migrations
 
-import _ "embed"
+import "embed"
 
-// Schema holds SQL for ensuring tables exist.
+// FS holds the ordered migration files, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql.
 //
-//go:embed 001_init.sql
-var Schema string
+//go:embed *.sql
+var FS embed.FS
@@ -1,57 +1,90 @@
+-- Claims for a batch of users on one campaign shard. Every key shares the
+-- shard's hash tag, so the script runs on one Redis Cluster slot.
+--
+-- KEYS[1] opened set, KEYS[2] window, KEYS[3] leaderboard, KEYS[4..] the
+-- shard's inventory counters.
+-- ARGV[1] now, ARGV[2] reserve flag, ARGV[3] number of users n,
+-- ARGV[4..3+n] user ids, ARGV[4+n..] the amounts of KEYS[4..], in order.
+--
+-- Returns one {status, amount, currency} per user. With the reserve flag set,
+-- a user who finds the shard sold out is still added to the opened set and
+-- gets RESERVED, so the caller can try the campaign's other shards.
 local opened_key = KEYS[1]
 local window_key = KEYS[2]
-local amounts_key = KEYS[3]
+local leaderboard_key = KEYS[3]
 
-local user_id = ARGV[1]
-local now = tonumber(ARGV[2]) or tonumber(redis.call('TIME')[1])
-local campaign_id = ARGV[3]
-
--- checking user eligibility
-if redis.call('SISMEMBER', opened_key, user_id) == 1 then
-    return {'ALREADY_OPENED', 0}
-end
+local now = tonumber(ARGV[1]) or tonumber(redis.call('TIME')[1])
+local reserve = ARGV[2] == '1'
+local n = tonumber(ARGV[3])
 
 -- checking campaign availability
-local window = redis.call('HMGET', window_key, 'start', 'end')
-if window[1] == false or window[2] == false then
-    return {'CAMPAIGN_NOT_FOUND', 0}
-end
-
+local window = redis.call('HMGET', window_key, 'start', 'end', 'currency', 'frozen')
 local start_ts = tonumber(window[1])
 local end_ts = tonumber(window[2])
+local currency = window[3] or ''
+local closed = nil
 if not start_ts or not end_ts then
-    return {'CAMPAIGN_NOT_FOUND', 0}
-end
-
-if now < start_ts or now > end_ts then
-    return {'CAMPAIGN_INACTIVE', 0}
+    closed = {'CAMPAIGN_NOT_FOUND', 0, ''}
+elseif window[4] == '1' or now < start_ts or now > end_ts then
+    -- settled campaigns are frozen regardless of clock skew between API nodes
+    closed = {'CAMPAIGN_INACTIVE', 0, currency}
 end
 
 math.randomseed(now)
 
-
-local amounts = redis.call('SMEMBERS', amounts_key)
-if #amounts == 0 then
-    return {'SOLD_OUT', 0}
+local amounts = {}
+for i = 4, #KEYS do
+    amounts[#amounts + 1] = {amount = ARGV[n + i], key = KEYS[i]}
 end
 
-while #amounts > 0 do
-    local idx = math.random(#amounts)
-    local amount = amounts[idx]
-    amounts[idx] = amounts[#amounts]
-    amounts[#amounts] = nil
+local sold_out = false
+local function take()
+    if sold_out then
+        return nil
+    end
+    local tiers = {}
+    for i = 1, #amounts do
+        tiers[i] = amounts[i]
+    end
+    while #tiers > 0 do
+        local idx = math.random(#tiers)
+        local tier = tiers[idx]
+        tiers[idx] = tiers[#tiers]
+        tiers[#tiers] = nil
 
-    local inv_key = 'campaign:' .. campaign_id .. ':inv:' .. amount
-    local remaining = tonumber(redis.call('GET', inv_key) or '0')
-    if remaining > 0 then
-        local new_count = redis.call('DECR', inv_key)
-        if new_count >= 0 then
+        local remaining = tonumber(redis.call('GET', tier.key) or '0')
+        if remaining > 0 then
+            local new_count = redis.call('DECR', tier.key)
+            if new_count >= 0 then
+                return tier.amount
+            end
+            redis.call('INCR', tier.key)
+        end
+    end
+    sold_out = true
+    return nil
+end
+
+local replies = {}
+for i = 1, n do
+    local user_id = ARGV[3 + i]
+    -- checking user eligibility
+    if redis.call('SISMEMBER', opened_key, user_id) == 1 then
+        replies[i] = {'ALREADY_OPENED', 0, ''}
+    elseif closed then
+        replies[i] = closed
+    else
+        local amount = take()
+        if amount then
+            redis.call('SADD', opened_key, user_id)
+            redis.call('ZADD', leaderboard_key, tonumber(amount), user_id)
+            replies[i] = {'OK', tonumber(amount), currency}
+        elseif reserve then
             redis.call('SADD', opened_key, user_id)
-            return {'OK', tonumber(amount)}
+            replies[i] = {'RESERVED', 0, currency}
         else
-            redis.call('INCR', inv_key)
+            replies[i] = {'SOLD_OUT', 0, currency}
         end
     end
 end
-
-return {'SOLD_OUT', 0}
+return replies
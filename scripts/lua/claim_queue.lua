@@ -0,0 +1,57 @@
+-- Queue-mode claims for a batch of users: every packet of the shard was
+-- shuffled into a list when the campaign was primed, so a claim pops one
+-- instead of scanning tiers.
+--
+-- KEYS[1] shard window, KEYS[2] its leaderboard, KEYS[3] its packet queue and
+-- KEYS[4], when given, the opened set of the users' home shard. It is omitted
+-- when taking from a neighbour shard for a user reserved elsewhere.
+-- ARGV[1] now, ARGV[2] reserve flag, ARGV[3..] user ids.
+--
+-- Returns one {status, amount, currency} per user. With the reserve flag set,
+-- a user who finds the home shard empty is kept in the opened set and gets
+-- RESERVED.
+local window_key = KEYS[1]
+local leaderboard_key = KEYS[2]
+local queue_key = KEYS[3]
+local opened_key = KEYS[4]
+
+local now = tonumber(ARGV[1]) or tonumber(redis.call('TIME')[1])
+local reserve = ARGV[2] == '1'
+
+local window = redis.call('HMGET', window_key, 'start', 'end', 'currency', 'frozen')
+local start_ts = tonumber(window[1])
+local end_ts = tonumber(window[2])
+local currency = window[3] or ''
+local closed = nil
+if not start_ts or not end_ts then
+    closed = {'CAMPAIGN_NOT_FOUND', 0, ''}
+elseif window[4] == '1' or now < start_ts or now > end_ts then
+    closed = {'CAMPAIGN_INACTIVE', 0, currency}
+end
+
+local replies = {}
+for i = 3, #ARGV do
+    local user_id = ARGV[i]
+    local reply
+    if opened_key and redis.call('SISMEMBER', opened_key, user_id) == 1 then
+        reply = {'ALREADY_OPENED', 0, ''}
+    elseif closed then
+        reply = closed
+    else
+        local amount = redis.call('LPOP', queue_key)
+        if amount then
+            if opened_key then
+                redis.call('SADD', opened_key, user_id)
+            end
+            redis.call('ZADD', leaderboard_key, tonumber(amount), user_id)
+            reply = {'OK', tonumber(amount), currency}
+        elseif opened_key and reserve then
+            redis.call('SADD', opened_key, user_id)
+            reply = {'RESERVED', 0, currency}
+        else
+            reply = {'SOLD_OUT', 0, currency}
+        end
+    end
+    replies[#replies + 1] = reply
+end
+return replies
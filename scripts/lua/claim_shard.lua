@@ -0,0 +1,47 @@
+-- Takes a packet from a neighbour shard for a user already reserved in their
+-- home shard's opened set. KEYS[1] is the shard window, KEYS[2] its
+-- leaderboard and KEYS[3..] its inventory counters for the amounts in
+-- ARGV[3..].
+local window_key = KEYS[1]
+local leaderboard_key = KEYS[2]
+
+local user_id = ARGV[1]
+local now = tonumber(ARGV[2]) or tonumber(redis.call('TIME')[1])
+
+local window = redis.call('HMGET', window_key, 'start', 'end', 'currency', 'frozen')
+local start_ts = tonumber(window[1])
+local end_ts = tonumber(window[2])
+if not start_ts or not end_ts then
+    return {'CAMPAIGN_NOT_FOUND', 0, ''}
+end
+local currency = window[3] or ''
+if window[4] == '1' or now < start_ts or now > end_ts then
+    return {'CAMPAIGN_INACTIVE', 0, currency}
+end
+
+math.randomseed(now)
+
+local tiers = {}
+for i = 3, #ARGV do
+    tiers[#tiers + 1] = {amount = ARGV[i], key = KEYS[i]}
+end
+
+while #tiers > 0 do
+    local idx = math.random(#tiers)
+    local tier = tiers[idx]
+    tiers[idx] = tiers[#tiers]
+    tiers[#tiers] = nil
+
+    local remaining = tonumber(redis.call('GET', tier.key) or '0')
+    if remaining > 0 then
+        local new_count = redis.call('DECR', tier.key)
+        if new_count >= 0 then
+            redis.call('ZADD', leaderboard_key, tonumber(tier.amount), user_id)
+            return {'OK', tonumber(tier.amount), currency}
+        else
+            redis.call('INCR', tier.key)
+        end
+    end
+end
+
+return {'SOLD_OUT', 0, currency}
@@ -2,7 +2,39 @@ package lua
 
 import _ "embed"
 
-// ClaimScript contains the Redis Lua script for claims.
+// ClaimScript claims for a batch of users on one inventory shard.
 //
 //go:embed claim.lua
 var ClaimScript string
+
+// LockAcquireScript takes or renews a lease lock held by ARGV[1].
+//
+//go:embed lock_acquire.lua
+var LockAcquireScript string
+
+// LockReleaseScript deletes a lease lock if ARGV[1] still holds it.
+//
+//go:embed lock_release.lua
+var LockReleaseScript string
+
+// ClaimShardScript takes a packet from a neighbour inventory shard.
+//
+//go:embed claim_shard.lua
+var ClaimShardScript string
+
+// ClaimQueueScript claims for a batch of users by popping a pre-shuffled
+// packet queue.
+//
+//go:embed claim_queue.lua
+var ClaimQueueScript string
+
+// QueueTicketScript issues a user's waiting room ticket, once per campaign.
+//
+//go:embed queue_ticket.lua
+var QueueTicketScript string
+
+// ReleaseClaimScript hands back a packet claimed for a user whose claim was
+// abandoned, reading its amount from the shard's leaderboard.
+//
+//go:embed release_claim.lua
+var ReleaseClaimScript string
@@ -0,0 +1,14 @@
+local lock_key = KEYS[1]
+
+local owner = ARGV[1]
+local ttl_ms = tonumber(ARGV[2])
+
+-- the current holder renews its lease; anyone else only gets a free lock
+if redis.call('GET', lock_key) == owner then
+    redis.call('PEXPIRE', lock_key, ttl_ms)
+    return 1
+end
+if redis.call('SET', lock_key, owner, 'NX', 'PX', ttl_ms) then
+    return 1
+end
+return 0
@@ -0,0 +1,10 @@
+-- Issues a waiting room ticket. KEYS[1] maps user ids to tickets and KEYS[2]
+-- counts the tickets issued; ARGV[1] is the user id. A user who already holds
+-- a ticket gets it back, so rejoining never moves them back in line.
+local ticket = redis.call('HGET', KEYS[1], ARGV[1])
+if ticket then
+    return tonumber(ticket)
+end
+ticket = redis.call('INCR', KEYS[2])
+redis.call('HSET', KEYS[1], ARGV[1], ticket)
+return ticket
@@ -0,0 +1,30 @@
+-- Hands back a packet a shard gave to a user whose claim was abandoned: a
+-- caller that stopped waiting before the reply arrived, or a neighbour-shard
+-- fallback that failed after the packet may have been taken. The amount is
+-- read from the shard's leaderboard, so the packet goes back even if the
+-- claim's reply was lost. Every key shares the shard's hash tag.
+--
+-- KEYS[1] opened set, KEYS[2] leaderboard, then KEYS[3] the packet queue in
+-- queue mode, or KEYS[3..] the inventory counters for the amounts in ARGV[3..]
+-- in counter mode.
+-- ARGV[1] user id, ARGV[2] claim mode.
+--
+-- Returns 1 if the packet went back, 0 if the user held none on the shard.
+local user_id = ARGV[1]
+local amount = redis.call('ZSCORE', KEYS[2], user_id)
+if not amount then
+    return 0
+end
+redis.call('ZREM', KEYS[2], user_id)
+redis.call('SREM', KEYS[1], user_id)
+if ARGV[2] == 'queue' then
+    redis.call('RPUSH', KEYS[3], amount)
+else
+    for i = 3, #ARGV do
+        if tonumber(ARGV[i]) == tonumber(amount) then
+            redis.call('INCR', KEYS[i])
+            break
+        end
+    end
+end
+return 1
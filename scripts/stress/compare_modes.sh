@@ -0,0 +1,62 @@
+#!/usr/bin/env bash
+# Runs the k6 open scenario against a counter-mode and a queue-mode campaign
+# with the same inventory and prints the claim latency of each.
+#
+#   BASE_URL=http://localhost:8080 TIERS=50 PACKETS=2000 ./scripts/stress/compare_modes.sh
+set -euo pipefail
+
+BASE_URL=${BASE_URL:-http://localhost:8080}
+TIERS=${TIERS:-50}
+PACKETS=${PACKETS:-2000}
+DURATION=${DURATION:-1m}
+RATE=${RATE:-5000}
+OUT=${OUT:-$(mktemp -d)}
+DIR=$(cd "$(dirname "$0")" && pwd)
+
+inventory() {
+  local entries=()
+  for ((i = 1; i <= TIERS; i++)); do
+    entries+=("\"$((i * 100))\": $PACKETS")
+  done
+  local IFS=,
+  echo "{${entries[*]}}"
+}
+
+create_campaign() {
+  local mode=$1
+  local start end
+  start=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+  end=$(date -u -d '+1 hour' +%Y-%m-%dT%H:%M:%SZ)
+  curl -sf -X POST "$BASE_URL/campaign" -H 'Content-Type: application/json' -d "{
+    \"name\": \"bench $mode\",
+    \"currency\": \"CNY\",
+    \"claim_mode\": \"$mode\",
+    \"start_time\": \"$start\",
+    \"end_time\": \"$end\",
+    \"inventory\": $(inventory)
+  }" | jq -r .id
+}
+
+wait_ready() {
+  until [ "$(curl -sf "$BASE_URL/campaign/$1" | jq -r .readiness.ready)" = "true" ]; do
+    sleep 1
+  done
+}
+
+for mode in counter queue; do
+  id=$(create_campaign "$mode")
+  wait_ready "$id"
+  echo "running $mode mode against campaign $id"
+  k6 run --quiet --summary-export "$OUT/$mode.json" \
+    -e BASE_URL="$BASE_URL" -e CAMPAIGNS="$id" -e DURATION="$DURATION" -e RATE="$RATE" \
+    "$DIR/k6_open.js" >/dev/null
+done
+
+printf '%-8s %10s %10s %10s %10s\n' mode reqs p50_ms p95_ms max_ms
+for mode in counter queue; do
+  jq -r --arg mode "$mode" '.metrics.http_req_duration as $d
+    | [$mode, .metrics.http_reqs.count, $d.med, $d["p(95)"], $d.max]
+    | @tsv' "$OUT/$mode.json" |
+    awk '{ printf "%-8s %10d %10.2f %10.2f %10.2f\n", $1, $2, $3, $4, $5 }'
+done
+echo "summaries in $OUT"
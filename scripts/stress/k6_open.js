@@ -1,15 +1,15 @@
 import http from 'k6/http';
 import { check } from 'k6';
 
-const campaigns = [1,2];
+const campaigns = (__ENV.CAMPAIGNS || '1,2').split(',').map((id) => parseInt(id, 10));
 const baseURL = __ENV.BASE_URL || 'http://localhost:8080';
 
 export const options = {
   scenarios: {
     open_red_packets: {
       executor: 'constant-arrival-rate',
-      duration: '2m',
-      rate: 5000,
+      duration: __ENV.DURATION || '2m',
+      rate: parseInt(__ENV.RATE || '5000', 10),
       timeUnit: '1s',
       preAllocatedVUs: 1500,
       maxVUs: 6000,